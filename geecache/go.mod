@@ -2,4 +2,9 @@ module geecache
 
 go 1.17
 
-require google.golang.org/protobuf v1.28.0
+require (
+	geetrace v0.0.0
+	google.golang.org/protobuf v1.28.0
+)
+
+replace geetrace => ../geetrace
@@ -1,6 +1,7 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
 	pb "geecache/geecachepb"
 	"geecache/singleflight"
@@ -50,6 +51,11 @@ func GetGroup(name string) *Group {
 }
 
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，ctx 会传给实现了 ContextPeerGetter 的节点
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
@@ -59,7 +65,7 @@ func (g *Group) Get(key string) (ByteView, error) {
 		return bv, nil
 	}
 
-	return g.load(key)
+	return g.load(ctx, key)
 }
 
 func (g *Group) RegisterPeer(peer PeerPicker) {
@@ -69,11 +75,12 @@ func (g *Group) RegisterPeer(peer PeerPicker) {
 	g.peer = peer
 }
 
-func (g *Group) load(key string) (bv ByteView, err error) {
+// load 合并并发的请求，只有第一个请求的 ctx 会传给节点
+func (g *Group) load(ctx context.Context, key string) (bv ByteView, err error) {
 	value, err := g.loader.Do(key, func() (interface{}, error) {
 		if g.peer != nil {
 			if peer, ok := g.peer.PickPeer(key); ok {
-				if value, err := g.getFromPeers(peer, key); err == nil {
+				if value, err := g.getFromPeers(ctx, peer, key); err == nil {
 					return value, err
 				}
 			}
@@ -86,13 +93,18 @@ func (g *Group) load(key string) (bv ByteView, err error) {
 	return
 }
 
-func (g *Group) getFromPeers(peer PeerGetter, key string) (ByteView, error) {
+func (g *Group) getFromPeers(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	var err error
+	if p, ok := peer.(ContextPeerGetter); ok {
+		err = p.GetContext(ctx, req, res)
+	} else {
+		err = peer.Get(req, res)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
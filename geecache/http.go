@@ -1,9 +1,11 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
 	"geecache/consistenthash"
 	pb "geecache/geecachepb"
+	"geetrace"
 	"google.golang.org/protobuf/proto"
 	"io/ioutil"
 	"log"
@@ -21,11 +23,27 @@ type PeerGetter interface {
 	Get(in *pb.Request, out *pb.Response) error
 }
 
+// ContextPeerGetter 可以随请求传递 context，比如链路信息，Group 优先使用它
+type ContextPeerGetter interface {
+	PeerGetter
+	GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
 type HttpGetter struct {
 	basePath string //
 }
 
 func (h *HttpGetter) Get(in *pb.Request, out *pb.Response) error {
+	return h.GetContext(context.Background(), in, out)
+}
+
+// GetContext 开始一个 client span，并通过 traceparent 和 tracestate 头传给远端节点
+func (h *HttpGetter) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) (err error) {
+	ctx, span := geetrace.Start(ctx, "geecache.Get "+in.GetGroup(), geetrace.SpanKindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 	if !strings.HasSuffix(h.basePath, "/") {
 		h.basePath += "/"
 	}
@@ -34,7 +52,13 @@ func (h *HttpGetter) Get(in *pb.Request, out *pb.Response) error {
 		h.basePath,
 		url.QueryEscape(in.GetGroup()),
 		url.QueryEscape(in.GetKey()))
-	resp, err := http.Get(u)
+	span.SetAttribute("geecache.peer", h.basePath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	geetrace.Inject(ctx, geetrace.HeaderCarrier(req.Header))
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -118,8 +142,14 @@ func (s *HttpPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := group.Get(parts[1])
+	// 延续请求方的链路，回源到其他节点或本地的 Getter 时也在同一条链路中
+	ctx := geetrace.Extract(r.Context(), geetrace.HeaderCarrier(r.Header))
+	ctx, span := geetrace.Start(ctx, "geecache.Serve "+parts[0], geetrace.SpanKindServer)
+	defer span.End()
+
+	data, err := group.GetContext(ctx, parts[1])
 	if err != nil {
+		span.SetError(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
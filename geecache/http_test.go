@@ -0,0 +1,45 @@
+package geecache
+
+import (
+	"context"
+	pb "geecache/geecachepb"
+	"geetrace"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHttpGetter_Tracing(t *testing.T) {
+	exporter := geetrace.NewInMemoryExporter()
+	geetrace.SetDefault(geetrace.NewTracer(exporter))
+	defer geetrace.SetDefault(geetrace.NewTracer(nil))
+	NewGroup(GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}), "tracing", 2<<10)
+	srv := httptest.NewServer(NewHttpPool(""))
+	defer srv.Close()
+
+	ctx, parent := geetrace.Start(context.Background(), "handler", geetrace.SpanKindInternal)
+	getter := &HttpGetter{basePath: srv.URL + defaultBasePath}
+	res := &pb.Response{}
+	if err := getter.GetContext(ctx, &pb.Request{Group: "tracing", Key: "Tom"}, res); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+	if string(res.Value) != "630" {
+		t.Fatalf("expect 630, got %q", res.Value)
+	}
+
+	spans := make(map[string]geetrace.SpanData)
+	for _, span := range exporter.Spans() {
+		spans[span.Kind] = span
+		if span.TraceID != parent.SpanContext().TraceID.String() {
+			t.Fatalf("span %s should be in the same trace", span.Name)
+		}
+	}
+	if spans["client"].ParentID != parent.SpanContext().SpanID.String() {
+		t.Fatalf("client span should be a child of the caller, got %+v", spans["client"])
+	}
+	if spans["server"].Name != "geecache.Serve tracing" || spans["server"].ParentID != spans["client"].SpanID {
+		t.Fatalf("server span should be a child of the client span, got %+v", spans["server"])
+	}
+}
@@ -7,6 +7,8 @@ type Call struct {
 	Replyv interface{}
 	Error  error
 	Done   chan *Call
+	// Metadata 随请求头发送，用于传递链路信息
+	Metadata map[string]string
 }
 
 func (c *Call) done() {
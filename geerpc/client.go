@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"geerpc/codec"
+	"geetrace"
 	"io"
 	"log"
 	"net"
@@ -59,6 +60,7 @@ func (client *Client) send(call *Call) {
 	client.header.ServiceMethod = call.Method
 	client.header.Seq = seq
 	client.header.Error = ""
+	client.header.Metadata = call.Metadata
 
 	if err = client.cc.Write(&client.header, call.Argv); err != nil {
 		call := client.removeCall(seq)
@@ -129,28 +131,41 @@ func (client *Client) terminateCalls(err error) {
 }
 
 func (client *Client) Do(method string, args, reply interface{}, done chan *Call) *Call {
+	return client.do(method, args, reply, done, nil)
+}
+
+func (client *Client) do(method string, args, reply interface{}, done chan *Call, metadata map[string]string) *Call {
 	if done == nil {
 		done = make(chan *Call, 10)
 	} else if cap(done) == 0 {
 		log.Panic("rpc client: done channel is unbuffered")
 	}
 	call := &Call{
-		Method: method,
-		Argv:   args,
-		Replyv: reply,
-		Done:   done,
+		Method:   method,
+		Argv:     args,
+		Replyv:   reply,
+		Done:     done,
+		Metadata: metadata,
 	}
 	client.send(call)
 	return call
 }
 
+// Call 开始一个 client span，并把 ctx 中的链路信息通过 Header.Metadata 传给服务端
 func (client *Client) Call(ctx context.Context, method string, args, reply interface{}) error {
-	call := client.Do(method, args, reply, nil)
+	ctx, span := geetrace.Start(ctx, method, geetrace.SpanKindClient)
+	defer span.End()
+	metadata := geetrace.MapCarrier{}
+	geetrace.Inject(ctx, metadata)
+	call := client.do(method, args, reply, nil, metadata)
 	select {
 	case <-ctx.Done():
 		client.removeCall(call.Seq)
-		return errors.New("rpc client: call failed: " + ctx.Err().Error())
+		err := errors.New("rpc client: call failed: " + ctx.Err().Error())
+		span.SetError(err)
+		return err
 	case call := <-call.Done:
+		span.SetError(call.Error)
 		return call.Error
 	}
 }
@@ -2,6 +2,7 @@ package geerpc
 
 import (
 	"context"
+	"geetrace"
 	"net"
 	"os"
 	"runtime"
@@ -67,6 +68,36 @@ func TestClient_Call(t *testing.T) {
 	})
 }
 
+func TestClient_CallTracing(t *testing.T) {
+	exporter := geetrace.NewInMemoryExporter()
+	geetrace.SetDefault(geetrace.NewTracer(exporter))
+	defer geetrace.SetDefault(geetrace.NewTracer(nil))
+	s := NewServer()
+	_ = s.Register(new(Foo))
+	l, _ := net.Listen("tcp", ":0")
+	go s.Accept(l)
+	client, err := Dial("tcp", l.Addr().String())
+	_assert(err == nil, "failed to dial: %v", err)
+	defer func() { _ = client.Close() }()
+
+	ctx, parent := geetrace.Start(context.Background(), "handler", geetrace.SpanKindInternal)
+	var reply int
+	err = client.Call(ctx, "Foo.Sum", Args{Num1: 1, Num2: 2}, &reply)
+	_assert(err == nil && reply == 3, "failed to call Foo.Sum: %v", err)
+	parent.End()
+
+	spans := exporter.Spans()
+	_assert(len(spans) == 3, "expect 3 spans, got %d", len(spans))
+	byKind := make(map[string]geetrace.SpanData)
+	for _, span := range spans {
+		byKind[span.Kind] = span
+		_assert(span.TraceID == parent.SpanContext().TraceID.String(), "span %s should be in the same trace", span.Name)
+	}
+	_assert(byKind["client"].ParentID == parent.SpanContext().SpanID.String(), "client span should be a child of the caller")
+	_assert(byKind["server"].Name == "Foo.Sum" && byKind["server"].ParentID == byKind["client"].SpanID,
+		"server span should be a child of the client span")
+}
+
 func TestXDial(t *testing.T) {
 	if runtime.GOOS == "linux" {
 		ch := make(chan struct{})
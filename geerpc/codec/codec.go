@@ -6,6 +6,7 @@ type Header struct {
 	ServiceMethod string // format "Service.Method"
 	Seq           uint64 // sequence number chosen by client
 	Error         string
+	Metadata      map[string]string // traceparent and tracestate from the caller, not sent back
 }
 
 type Codec interface {
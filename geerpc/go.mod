@@ -1,3 +1,7 @@
 module geerpc
 
 go 1.17
+
+require geetrace v0.0.0
+
+replace geetrace => ../geetrace
@@ -1,11 +1,13 @@
 package geerpc
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"geerpc/codec"
+	"geetrace"
 	"io"
 	"log"
 	"net"
@@ -191,10 +193,19 @@ func (s *Server) sendResponse(c codec.Codec, header *codec.Header, resp interfac
 
 func (s *Server) handleRequest(c codec.Codec, req *request, wg *sync.WaitGroup, sending *sync.Mutex, timeout time.Duration) {
 	defer wg.Done()
+	// 服务端 span 延续客户端的链路，Metadata 不随响应返回
+	parent := geetrace.Extract(context.Background(), geetrace.MapCarrier(req.h.Metadata))
+	req.h.Metadata = nil
+	// 服务端超时或处理完成之后取消，接收 context.Context 的方法可以据此提前结束
+	parent, cancel := context.WithCancel(parent)
+	defer cancel()
 	called := make(chan struct{})
 	sent := make(chan struct{})
 	go func() {
-		err := req.svc.call(req.mtype, req.argv, req.replyv)
+		ctx, span := geetrace.Start(parent, req.h.ServiceMethod, geetrace.SpanKindServer)
+		err := req.svc.call(ctx, req.mtype, req.argv, req.replyv)
+		span.SetError(err)
+		span.End()
 		close(called)
 		if err != nil {
 			req.h.Error = err.Error()
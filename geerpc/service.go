@@ -1,6 +1,7 @@
 package geerpc
 
 import (
+	"context"
 	"go/ast"
 	"log"
 	"reflect"
@@ -8,12 +9,18 @@ import (
 )
 
 type methodType struct {
-	method    reflect.Method
-	ArgType   reflect.Type
-	ReplyType reflect.Type
-	NumCalls  uint64
+	method      reflect.Method
+	ArgType     reflect.Type
+	ReplyType   reflect.Type
+	NumCalls    uint64
+	withContext bool // 第一个参数是 context.Context
 }
 
+var (
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
 func (m *methodType) newArgv() reflect.Value {
 	var argv reflect.Value
 	if m.ArgType.Kind() == reflect.Ptr {
@@ -54,34 +61,46 @@ func newService(v interface{}) *service {
 	return s
 }
 
+// registerMethods 注册 func (t *T) Method(args T1, reply *T2) error 形式的方法，
+// 第一个参数也可以是 context.Context，其中带有请求的链路信息，并在服务端超时后被取消
 func (s *service) registerMethods() {
 	s.method = make(map[string]*methodType)
 	for i := 0; i < s.rcvr.NumMethod(); i++ {
 		method := s.typ.Method(i)
 		mType := method.Type
-		if mType.NumIn() != 3 && mType.NumOut() != 1 {
+		if mType.NumOut() != 1 || mType.Out(0) != typeOfError {
 			continue
 		}
-		if mType.Out(0) != reflect.TypeOf((*error)(nil)).Elem() {
+		withContext := mType.NumIn() == 4 && mType.In(1) == typeOfContext
+		if mType.NumIn() != 3 && !withContext {
 			continue
 		}
-		argType, replyType := mType.In(1), mType.In(2)
+		argIndex := 1
+		if withContext {
+			argIndex = 2
+		}
+		argType, replyType := mType.In(argIndex), mType.In(argIndex+1)
 		if !isExportedOrBuiltinType(argType) || !isExportedOrBuiltinType(replyType) {
 			continue
 		}
 		s.method[method.Name] = &methodType{
-			method:    method,
-			ArgType:   argType,
-			ReplyType: replyType,
+			method:      method,
+			ArgType:     argType,
+			ReplyType:   replyType,
+			withContext: withContext,
 		}
 		log.Printf("rpc server: register %s.%s\n", s.name, method.Name)
 	}
 }
 
-func (s *service) call(m *methodType, argv, replyv reflect.Value) error {
+func (s *service) call(ctx context.Context, m *methodType, argv, replyv reflect.Value) error {
 	atomic.AddUint64(&m.NumCalls, 1)
 	f := m.method.Func
-	returnValues := f.Call([]reflect.Value{s.rcvr, argv, replyv})
+	in := []reflect.Value{s.rcvr, argv, replyv}
+	if m.withContext {
+		in = []reflect.Value{s.rcvr, reflect.ValueOf(ctx), argv, replyv}
+	}
+	returnValues := f.Call(in)
 	if errInter := returnValues[0].Interface(); errInter != nil {
 		return errInter.(error)
 	}
@@ -1,6 +1,7 @@
 package geerpc
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -20,6 +21,14 @@ func (f *Foo) sum(args Args, reply *int) error {
 	return nil
 }
 
+func (f *Foo) SumContext(ctx context.Context, args Args, reply *int) error {
+	if ctx == nil {
+		return fmt.Errorf("context should be passed")
+	}
+	*reply = args.Num1 + args.Num2
+	return nil
+}
+
 func _assert(condition bool, msgfmt string, v ...interface{}) {
 	if !condition {
 		panic(fmt.Sprintf("assertion failed: "+msgfmt, v...))
@@ -29,7 +38,7 @@ func _assert(condition bool, msgfmt string, v ...interface{}) {
 func TestNewService(t *testing.T) {
 	var foo Foo
 	s := newService(&foo)
-	_assert(len(s.method) == 1, "wrong service Method, expect 1, but got %d", len(s.method))
+	_assert(len(s.method) == 2, "wrong service Method, expect 2, but got %d", len(s.method))
 	mType := s.method["Sum"]
 	_assert(mType != nil, "wrong Method, Sum shouldn't nil")
 }
@@ -41,6 +50,18 @@ func TestMethodType_Call(t *testing.T) {
 	argv := mType.newArgv()
 	replyv := mType.newReplyv()
 	argv.Set(reflect.ValueOf(Args{Num1: 1, Num2: 3}))
-	err := s.call(mType, argv, replyv)
+	err := s.call(context.Background(), mType, argv, replyv)
 	_assert(err == nil && *replyv.Interface().(*int) == 4 && mType.NumCalls == 1, "failed to call Foo.Sum")
 }
+
+func TestMethodType_CallWithContext(t *testing.T) {
+	var foo Foo
+	s := newService(&foo)
+	mType := s.method["SumContext"]
+	_assert(mType != nil && mType.withContext, "SumContext should take a context")
+	argv := mType.newArgv()
+	replyv := mType.newReplyv()
+	argv.Set(reflect.ValueOf(Args{Num1: 1, Num2: 3}))
+	err := s.call(context.Background(), mType, argv, replyv)
+	_assert(err == nil && *replyv.Interface().(*int) == 4, "failed to call Foo.SumContext: %v", err)
+}
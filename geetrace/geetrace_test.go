@@ -0,0 +1,125 @@
+package geetrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.IsSampled() {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if got := sc.TraceParent(); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("round trip got %q", got)
+	}
+	if _, err := ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil {
+		t.Fatalf("future versions with extra fields should parse: %v", err)
+	}
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceParent(s); err == nil {
+			t.Fatalf("%q should be rejected", s)
+		}
+	}
+}
+
+func TestParseTraceState(t *testing.T) {
+	if got := ParseTraceState("rojo=00f067aa0ba902b7, congo=t61rcWkgMzE"); got != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Fatalf("unexpected tracestate %q", got)
+	}
+	for _, s := range []string{"Rojo=1", "rojo=1,rojo=2", "rojo", "=1"} {
+		if got := ParseTraceState(s); got != "" {
+			t.Fatalf("%q should be dropped, got %q", s, got)
+		}
+	}
+}
+
+func TestStartChildSpan(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("peer", "localhost")
+	child.SetError(errors.New("boom"))
+	child.End()
+	child.End()
+	parent.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expect 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name != "child" || spans[0].Kind != "client" || spans[0].Error != "boom" || spans[0].Attributes["peer"] != "localhost" {
+		t.Fatalf("unexpected child span %+v", spans[0])
+	}
+	if spans[0].TraceID != spans[1].TraceID || spans[0].ParentID != spans[1].SpanID || spans[1].ParentID != "" {
+		t.Fatalf("child should belong to parent: %+v %+v", spans[0], spans[1])
+	}
+	exporter.Reset()
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("Reset should drop all spans")
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(exporter)
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Set("tracestate", "rojo=00f067aa0ba902b7")
+	ctx := Extract(context.Background(), HeaderCarrier(header))
+	if sc := SpanContextFromContext(ctx); !sc.Remote || sc.TraceState != "rojo=00f067aa0ba902b7" {
+		t.Fatalf("unexpected remote span context %+v", sc)
+	}
+
+	ctx, span := tracer.Start(ctx, "server", SpanKindServer)
+	carrier := MapCarrier{}
+	Inject(ctx, carrier)
+	span.End()
+	if carrier["traceparent"] != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+span.SpanContext().SpanID.String()+"-01" {
+		t.Fatalf("unexpected traceparent %q", carrier["traceparent"])
+	}
+	if carrier["tracestate"] != "rojo=00f067aa0ba902b7" {
+		t.Fatalf("tracestate should be propagated, got %q", carrier["tracestate"])
+	}
+	if spans := exporter.Spans(); spans[0].ParentID != "00f067aa0ba902b7" {
+		t.Fatalf("server span should continue the remote trace, got %+v", spans[0])
+	}
+
+	// 未采样的链路照常传递，但不导出
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	exporter.Reset()
+	_, span = tracer.Start(Extract(context.Background(), HeaderCarrier(header)), "server", SpanKindServer)
+	span.End()
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("unsampled spans should not be exported")
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	_, span := NewTracer(NewStdoutExporter(&buf)).Start(context.Background(), "job", SpanKindInternal)
+	span.End()
+	var data SpanData
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Name != "job" || data.Kind != "internal" || data.TraceID != span.SpanContext().TraceID.String() {
+		t.Fatalf("unexpected exported span %+v", data)
+	}
+}
@@ -0,0 +1,3 @@
+module geetrace
+
+go 1.17
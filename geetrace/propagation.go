@@ -0,0 +1,44 @@
+package geetrace
+
+import (
+	"context"
+	"net/http"
+)
+
+// Carrier 是传递 traceparent 和 tracestate 的载体，比如 HTTP 头部或 RPC 的元数据
+type Carrier interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+type HeaderCarrier http.Header
+
+func (c HeaderCarrier) Get(key string) string { return http.Header(c).Get(key) }
+func (c HeaderCarrier) Set(key, value string) { http.Header(c).Set(key, value) }
+
+type MapCarrier map[string]string
+
+func (c MapCarrier) Get(key string) string { return c[key] }
+func (c MapCarrier) Set(key, value string) { c[key] = value }
+
+// Inject 把 ctx 中的 SpanContext 写入 carrier，没有时什么都不做
+func Inject(ctx context.Context, carrier Carrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	carrier.Set(TraceParentHeader, sc.TraceParent())
+	if sc.TraceState != "" {
+		carrier.Set(TraceStateHeader, sc.TraceState)
+	}
+}
+
+// Extract 从 carrier 中读取远端的 SpanContext，traceparent 不合法时忽略，tracestate 也一起丢弃
+func Extract(ctx context.Context, carrier Carrier) context.Context {
+	sc, err := ParseTraceParent(carrier.Get(TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = ParseTraceState(carrier.Get(TraceStateHeader))
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
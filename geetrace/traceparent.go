@@ -0,0 +1,147 @@
+package geetrace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// TraceID 和 SpanID 的格式见 https://www.w3.org/TR/trace-context/
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (t TraceID) IsValid() bool  { return t != TraceID{} }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+func (s SpanID) IsValid() bool   { return s != SpanID{} }
+
+// FlagsSampled 表示调用方已经决定记录这条链路
+const FlagsSampled byte = 0x01
+
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+var ErrInvalidTraceParent = errors.New("geetrace: invalid traceparent")
+
+// SpanContext 是需要跨进程传递的部分
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote 表示 SpanContext 是从其他进程传递过来的
+	Remote bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagsSampled != 0
+}
+
+// TraceParent 返回 version-traceid-parentid-flags 格式的头部值
+func (sc SpanContext) TraceParent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// ParseTraceParent 解析 traceparent，更高的版本只要前缀格式一致也可以解析
+func ParseTraceParent(s string) (SpanContext, error) {
+	var sc SpanContext
+	s = strings.TrimSpace(s)
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, ErrInvalidTraceParent
+	}
+	version, err := decodeLowerHex(s[0:2])
+	if err != nil || version[0] == 0xff {
+		return sc, ErrInvalidTraceParent
+	}
+	// 版本 00 必须恰好 55 个字符，更高的版本之后可以有 - 开头的扩展字段
+	if len(s) > 55 && (version[0] == 0 || s[55] != '-') {
+		return sc, ErrInvalidTraceParent
+	}
+	traceID, err := decodeLowerHex(s[3:35])
+	if err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	spanID, err := decodeLowerHex(s[36:52])
+	if err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	flags, err := decodeLowerHex(s[53:55])
+	if err != nil {
+		return sc, ErrInvalidTraceParent
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0] & FlagsSampled
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceParent
+	}
+	return sc, nil
+}
+
+// decodeLowerHex 只接受小写，规范要求大写视为无效
+func decodeLowerHex(s string) ([]byte, error) {
+	if strings.ToLower(s) != s {
+		return nil, ErrInvalidTraceParent
+	}
+	return hex.DecodeString(s)
+}
+
+// ParseTraceState 校验 tracestate，格式不合法时整个丢弃，最多保留 32 个成员
+func ParseTraceState(s string) string {
+	var members []string
+	seen := make(map[string]bool)
+	for _, member := range strings.Split(s, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		i := strings.IndexByte(member, '=')
+		if i <= 0 || i == len(member)-1 || len(member) > 256 {
+			return ""
+		}
+		key := member[:i]
+		if !validStateKey(key) || seen[key] {
+			return ""
+		}
+		seen[key] = true
+		members = append(members, member)
+	}
+	if len(members) > 32 {
+		members = members[:32]
+	}
+	return strings.Join(members, ",")
+}
+
+func validStateKey(key string) bool {
+	if len(key) > 256 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '*' || c == '/' || c == '@') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return
+}
@@ -0,0 +1,52 @@
+package geetrace
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Tracer 创建 span，结束的 span 交给 exporter，exporter 为 nil 时只传递上下文不导出
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exporter Exporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// Start 在 ctx 中的 span 或远端 SpanContext 之下开始新的 span，没有时开始新的链路
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := SpanContextFromContext(ctx)
+	span := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, TraceState: parent.TraceState}
+		span.parent = parent.SpanID
+	} else {
+		span.sc = SpanContext{TraceID: newTraceID(), Flags: FlagsSampled}
+	}
+	span.sc.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+var defaultTracer atomic.Value
+
+func init() {
+	defaultTracer.Store(NewTracer(nil))
+}
+
+// SetDefault 设置 Start 使用的全局 Tracer，geeweb、geerpc 和 geecache 都使用它
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+func Default() *Tracer {
+	return defaultTracer.Load().(*Tracer)
+}
+
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return Default().Start(ctx, name, kind)
+}
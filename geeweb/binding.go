@@ -0,0 +1,308 @@
+package geeweb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// Binding 把请求中的数据填充到结构体中，填充之后由 validate 根据 binding 标签校验
+type Binding interface {
+	Name() string
+	Bind(req *http.Request, obj interface{}) error
+}
+
+var (
+	BindingJSON   Binding = jsonBinding{}
+	BindingForm   Binding = formBinding{}
+	BindingQuery  Binding = queryBinding{}
+	BindingHeader Binding = headerBinding{}
+)
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string { return "json" }
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return errors.New("geeweb: invalid request")
+	}
+	return json.NewDecoder(req.Body).Decode(obj)
+}
+
+type formBinding struct{}
+
+func (formBinding) Name() string { return "form" }
+
+// Bind 同时包括 query 参数和 body 中的表单
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	return mapForm(obj, req.Form, "form")
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string { return "query" }
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapForm(obj, req.URL.Query(), "form")
+}
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string { return "header" }
+
+// Bind 的 header 标签不区分大小写
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	values := make(map[string][]string, len(req.Header))
+	for k, v := range req.Header {
+		values[http.CanonicalHeaderKey(k)] = v
+	}
+	return mapFormWith(obj, "header", func(key string) ([]string, bool) {
+		v, ok := values[http.CanonicalHeaderKey(key)]
+		return v, ok
+	})
+}
+
+// bindingFor 根据请求方法和 Content-Type 选择 Binding
+func bindingFor(method, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete {
+		return BindingQuery
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	switch strings.TrimSpace(contentType) {
+	case "application/json":
+		return BindingJSON
+	default:
+		return BindingForm
+	}
+}
+
+// Bind 绑定失败时记录 ErrorTypeBind 错误，返回 400 并终止处理链
+func (c *Context) Bind(obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+func (c *Context) ShouldBind(obj interface{}) error {
+	return c.ShouldBindWith(obj, bindingFor(c.Method, c.Request.Header.Get("Content-Type")))
+}
+
+func (c *Context) ShouldBindWith(obj interface{}, b Binding) error {
+	if err := b.Bind(c.Request, obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingJSON)
+}
+
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingQuery)
+}
+
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return c.ShouldBindWith(obj, BindingHeader)
+}
+
+// ShouldBindUri 使用 uri 标签绑定路由中的 :param 和 *wildcard
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	err := mapFormWith(obj, "uri", func(key string) ([]string, bool) {
+		v, ok := c.Params[key]
+		return []string{v}, ok
+	})
+	if err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+func mapForm(obj interface{}, values map[string][]string, tag string) error {
+	return mapFormWith(obj, tag, func(key string) ([]string, bool) {
+		v, ok := values[key]
+		return v, ok
+	})
+}
+
+func mapFormWith(obj interface{}, tag string, lookup func(key string) ([]string, bool)) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("geeweb: binding requires a non-nil pointer to struct")
+	}
+	return mapStruct(v.Elem(), tag, lookup)
+}
+
+func mapStruct(v reflect.Value, tag string, lookup func(key string) ([]string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // 未导出字段
+		}
+		name := field.Tag.Get(tag)
+		if idx := strings.IndexByte(name, ','); idx >= 0 {
+			name = name[:idx]
+		}
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		// 没有标签的结构体字段（包括匿名字段）展开绑定
+		if name == "" && fv.Kind() == reflect.Struct && field.Type != timeType {
+			if err := mapStruct(fv, tag, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		values, ok := lookup(name)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if err := setField(fv, field, values); err != nil {
+			return fmt.Errorf("geeweb: binding field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func setField(v reflect.Value, field reflect.StructField, values []string) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setValue(slice.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) != v.Len() {
+			return fmt.Errorf("%q is not valid value for %s", values, v.Type())
+		}
+		for i, s := range values {
+			if err := setValue(v.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setValue(v, field, values[0])
+}
+
+func setValue(v reflect.Value, field reflect.StructField, s string) error {
+	switch v.Type() {
+	case timeType:
+		return setTime(v, field, s)
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), field, s); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// setTime 通过 time_format 标签指定格式，unix 和 unixnano 表示时间戳，默认为 RFC3339
+func setTime(v reflect.Value, field reflect.StructField, s string) error {
+	if s == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	format := field.Tag.Get("time_format")
+	switch format {
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if format == "unixnano" {
+			t = time.Unix(0, n)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case "":
+		format = time.RFC3339
+	}
+	t, err := time.Parse(format, s)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
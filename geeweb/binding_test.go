@@ -0,0 +1,142 @@
+package geeweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pageQuery struct {
+	Page  int       `form:"page" binding:"omitempty,min=1"`
+	Size  int       `form:"size" binding:"max=64"`
+	Tags  []string  `form:"tag"`
+	Since time.Time `form:"since" time_format:"2006-01-02"`
+	Debug bool      `form:"debug"`
+	Sort  string    `form:"sort" binding:"omitempty,oneof=asc desc"`
+}
+
+type createUser struct {
+	Name  string `json:"name" binding:"required,max=8"`
+	Email string `json:"email" binding:"required,email"`
+	Age   int    `json:"age" binding:"omitempty,min=1"`
+}
+
+func TestShouldBindQuery(t *testing.T) {
+	e := New()
+	var q pageQuery
+	e.GET("/posts", func(ctx *Context) {
+		if err := ctx.ShouldBind(&q); err != nil {
+			t.Fatal(err)
+		}
+	})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet,
+		"/posts?page=2&size=10&tag=go&tag=web&since=2022-05-01&debug=true&sort=asc", nil))
+	expect := pageQuery{
+		Page:  2,
+		Size:  10,
+		Tags:  []string{"go", "web"},
+		Since: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+		Debug: true,
+		Sort:  "asc",
+	}
+	if !reflect.DeepEqual(q, expect) {
+		t.Fatalf("expect %+v, got %+v", expect, q)
+	}
+}
+
+func TestShouldBindJSONValidation(t *testing.T) {
+	e := New()
+	e.POST("/users", func(ctx *Context) {
+		var u createUser
+		if err := ctx.Bind(&u); err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, u.Name)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"geektutu","email":"a@b.com","age":3}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("valid user got %d %q", w.Code, w.Body.String())
+	}
+
+	var u createUser
+	ctx := &Context{Request: httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name":"a very long name","email":"nope"}`))}
+	err := ctx.ShouldBindJSON(&u)
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expect 2 validation errors, got %v", err)
+	}
+	if errs[0].Field != "createUser.Name" || errs[0].Tag != "max" || errs[1].Tag != "email" {
+		t.Fatalf("unexpected validation errors %v", errs)
+	}
+}
+
+func TestShouldBindForm(t *testing.T) {
+	var q pageQuery
+	form := url.Values{"page": {"0"}, "sort": {"up"}}
+	req := httptest.NewRequest(http.MethodPost, "/posts?size=100", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	ctx := &Context{Method: req.Method, Request: req}
+	err := ctx.ShouldBind(&q)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 {
+		t.Fatalf("page=0 is skipped as zero value, size and sort should fail, got %v", err)
+	}
+	if q.Size != 100 || q.Sort != "up" {
+		t.Fatalf("form and query should both be bound, got %+v", q)
+	}
+}
+
+func TestShouldBindUriAndHeader(t *testing.T) {
+	type target struct {
+		ID      int    `uri:"id" header:"-" binding:"required"`
+		Token   string `header:"x-token" binding:"required"`
+		Retries uint8  `header:"X-Retries"`
+	}
+	e := New()
+	e.GET("/users/:id", func(ctx *Context) {
+		var v target
+		if err := ctx.ShouldBindHeader(&v); err == nil {
+			t.Fatal("id is required")
+		}
+		if err := ctx.ShouldBindUri(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.ID != 42 || v.Token != "abc" || v.Retries != 3 {
+			t.Fatalf("unexpected binding %+v", v)
+		}
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("X-Token", "abc")
+	req.Header.Set("X-Retries", "3")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestValidateZeroValue(t *testing.T) {
+	type strict struct {
+		Age  int    `binding:"min=1"`
+		Sort string `binding:"oneof=asc desc"`
+	}
+	type optional struct {
+		Age  int    `binding:"omitempty,min=1"`
+		Sort string `binding:"omitempty,oneof=asc desc"`
+	}
+	// 没有 omitempty 时规则对零值同样生效
+	errs, ok := validate(&strict{}).(ValidationErrors)
+	if !ok || len(errs) != 2 || errs[0].Tag != "min" || errs[1].Tag != "oneof" {
+		t.Fatalf("age=0 should fail on min=1 and empty sort on oneof, got %v", errs)
+	}
+	if err := validate(&optional{}); err != nil {
+		t.Fatalf("omitempty should skip zero values, got %v", err)
+	}
+	// omitempty 只跳过零值
+	if errs, ok := validate(&optional{Age: -1, Sort: "up"}).(ValidationErrors); !ok || len(errs) != 2 {
+		t.Fatalf("non-zero values should still be checked, got %v", errs)
+	}
+}
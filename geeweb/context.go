@@ -1,36 +1,171 @@
 package geeweb
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"gee/render"
+	"html/template"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template/parse"
+	"time"
 )
 
 type H map[string]interface{}
 
 // Context 为什么字段要导出？
 type Context struct {
-	Response http.ResponseWriter
+	Response ResponseWriter
 	Request  *http.Request
 
 	Method string
 	Path   string
 	Params map[string]string
-
+	// StatusCode 与 Response.Status() 同步，只读，设置状态码使用 Status 或 Response.WriteHeader
 	StatusCode int
+	// fullPath 是匹配到的路由，比如 /users/:id
+	fullPath string
 
 	handlers []HandlerFunc
 	index    int
 
-	engine *Engine
+	// Keys 用于在中间件和 handler 之间传递数据，比如鉴权得到的用户信息
+	Keys map[string]interface{}
+	// Errors 是通过 Error 收集的错误
+	Errors errorMsgs
+	mu     sync.RWMutex
+
+	writer   responseWriter
+	engine   *Engine
+	sameSite http.SameSite
+	funcMap  template.FuncMap
+	// busy 在 Timeout 之后仍在运行的处理链返回时关闭，在此之前 Context 不能放回 pool
+	busy chan struct{}
 }
 
+// abortIndex 远大于任何一条处理链的长度，index 置为它之后剩下的 handler 都不会执行
+const abortIndex int = math.MaxInt16
+
+// Next 依次执行剩余的 handler，中间件可以不调用 Next，此时后续的 handler 依然会执行，除非调用了 Abort
 func (c *Context) Next() {
 	c.index++
-	// middleware 应该要求一定包含 Next（），并且 handler 在最后，所以此处不必循环
-	if c.index < len(c.handlers) {
+	for c.index < len(c.handlers) {
 		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort 阻止执行后续的 handler，但不会中断当前 handler
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Abort()
+}
+
+func (c *Context) AbortWithStatusJSON(code int, v interface{}) {
+	c.Abort()
+	c.JSON(code, v)
+}
+
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, exists = c.Keys[key]
+	return
+}
+
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("geeweb: key \"" + key + "\" does not exist")
+}
+
+func (c *Context) GetString(key string) (s string) {
+	if value, ok := c.Get(key); ok && value != nil {
+		s, _ = value.(string)
+	}
+	return
+}
+
+func (c *Context) GetInt(key string) (i int) {
+	if value, ok := c.Get(key); ok && value != nil {
+		i, _ = value.(int)
+	}
+	return
+}
+
+func (c *Context) GetBool(key string) (b bool) {
+	if value, ok := c.Get(key); ok && value != nil {
+		b, _ = value.(bool)
+	}
+	return
+}
+
+// 以下方法使 Context 实现 context.Context，可以直接传给 geerpc 的 Client.Call
+// 注意 Context 会被复用，不能在 handler 返回之后继续使用
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Request == nil {
+		return
+	}
+	return c.Request.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Done()
+}
+
+func (c *Context) Err() error {
+	if c.Request == nil {
+		return nil
 	}
+	return c.Request.Context().Err()
+}
+
+// Value 优先查找 Keys 中的数据
+func (c *Context) Value(key interface{}) interface{} {
+	if keyAsString, ok := key.(string); ok {
+		if value, exists := c.Get(keyAsString); exists {
+			return value
+		}
+	}
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Value(key)
+}
+
+// ClientGone 在客户端断开连接之后返回 true，耗时的 handler 可以据此提前结束
+func (c *Context) ClientGone() bool {
+	return c.Request != nil && c.Request.Context().Err() == context.Canceled
+}
+
+// FullPath 返回匹配到的路由 pattern，比如 /users/:id，没有匹配到路由时返回空字符串
+func (c *Context) FullPath() string {
+	return c.fullPath
 }
 
 func (c *Context) Param(key string) string {
@@ -39,14 +174,71 @@ func (c *Context) Param(key string) string {
 }
 
 func (c *Context) Status(code int) {
-	c.StatusCode = code
 	c.Response.WriteHeader(code)
+	c.StatusCode = c.Response.Status()
 }
 
 func (c *Context) SetHeader(key string, value string) {
 	c.Response.Header().Set(key, value)
 }
 
+// ClientIP 只有在直接连接的地址属于可信代理时才使用 X-Forwarded-For 和 X-Real-IP
+func (c *Context) ClientIP() string {
+	remoteIP, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		remoteIP = c.Request.RemoteAddr
+	}
+	if c.engine == nil || !c.engine.isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+	// 从右往左找到第一个不可信的地址，它才是真正的客户端
+	forwarded := strings.Split(c.Request.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+		if i == 0 || !c.engine.isTrustedProxy(ip) {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(c.Request.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return remoteIP
+}
+
+// SetSameSite 设置之后 SetCookie 写出的 Cookie 的 SameSite 属性
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie 添加 Set-Cookie 头部，maxAge 小于 0 表示删除，等于 0 表示会话 Cookie
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.Response, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Cookie 返回请求中名为 name 的 Cookie 的值，不存在时返回 http.ErrNoCookie
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
 func (c *Context) PostForm(key string) string {
 	return c.Request.FormValue(key)
 }
@@ -55,46 +247,114 @@ func (c *Context) Query(key string) string {
 }
 
 func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	c.Response.Write(data)
+	c.Render(code, render.Data{Data: data})
+}
+
+// SetTemplateFunc 覆盖本次请求渲染模板时使用的函数，比如和请求相关的 CSRF token，
+// 函数名必须已经通过 Engine.SetFuncMap 注册，否则模板无法解析
+func (c *Context) SetTemplateFunc(name string, fn interface{}) {
+	if c.funcMap == nil {
+		c.funcMap = make(template.FuncMap)
+	}
+	c.funcMap[name] = fn
 }
 
 func (c *Context) HTML(code int, templateName string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Response, templateName, data); err != nil {
-		panic("HTML render failed")
+	tmpl := c.engine.htmlTemplates
+	if c.funcMap != nil && c.engine.htmlBase != nil && c.engine.htmlBase.Lookup(templateName) != nil {
+		// 复制要渲染的模板之后替换函数，不影响其他请求
+		clone, err := cloneTemplate(c.engine.htmlBase, templateName, c.engine.funcMap, c.funcMap)
+		if err != nil {
+			c.renderError(err)
+			return
+		}
+		tmpl = clone
 	}
+	c.Render(code, render.HTML{Template: tmpl, Name: templateName, Data: data})
+}
+
+// cloneTemplate 只复制 name 和它通过 {{template}} 引用的模板，而不是整个模板集合。
+// html/template 转义时会修改语法树，所以语法树也需要复制
+func cloneTemplate(base *template.Template, name string, funcMaps ...template.FuncMap) (*template.Template, error) {
+	t := template.New(name)
+	for _, funcMap := range funcMaps {
+		t.Funcs(funcMap)
+	}
+	queue := []string{name}
+	seen := map[string]bool{name: true}
+	for len(queue) > 0 {
+		src := base.Lookup(queue[0])
+		queue = queue[1:]
+		if src == nil || src.Tree == nil {
+			continue
+		}
+		tree := src.Tree.Copy()
+		if _, err := t.AddParseTree(src.Name(), tree); err != nil {
+			return nil, err
+		}
+		for _, ref := range templateRefs(tree.Root, nil) {
+			if !seen[ref] {
+				seen[ref] = true
+				queue = append(queue, ref)
+			}
+		}
+	}
+	return t, nil
+}
+
+// templateRefs 返回 node 中 {{template}} 引用的模板名
+func templateRefs(node parse.Node, refs []string) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return refs
+		}
+		for _, child := range n.Nodes {
+			refs = templateRefs(child, refs)
+		}
+	case *parse.TemplateNode:
+		refs = append(refs, n.Name)
+	case *parse.IfNode:
+		refs = templateRefs(n.ElseList, templateRefs(n.List, refs))
+	case *parse.RangeNode:
+		refs = templateRefs(n.ElseList, templateRefs(n.List, refs))
+	case *parse.WithNode:
+		refs = templateRefs(n.ElseList, templateRefs(n.List, refs))
+	}
+	return refs
 }
 
 // String 方法不会使输出 %v 时也调用此函数，因为接口方法签名不一致
 func (c *Context) String(code int, format string, v ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
-	c.Status(code)
-	c.Response.Write([]byte(fmt.Sprintf(format, v...)))
+	c.Render(code, render.String{Format: format, Data: v})
 }
 
+// JSON 先编码到缓冲区，编码失败时返回 500 而不是写出一半的响应
 func (c *Context) JSON(code int, v interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Response)
-	// 值得注意的是，这里的 error 其实并不会起作用，gin 中如果写入 json 发生错误的话会产生恐慌
-	if err := encoder.Encode(v); err != nil {
-		http.Error(c.Response, err.Error(), 500)
-	}
+	c.Render(code, render.JSON{Data: v})
 }
 
 func (c *Context) Fail(code int, err string) {
-	c.String(code, err)
+	c.String(code, "%s", err)
 }
 
-func newContext(resp http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Response: resp,
-		Request:  req,
-		Path:     req.URL.Path,
-		Method:   req.Method,
-		handlers: make([]HandlerFunc, 0),
-		index:    -1,
-	}
+// reset 清空上一次请求留下的状态，Context 由 Engine 的 sync.Pool 复用
+func (c *Context) reset(resp http.ResponseWriter, req *http.Request) {
+	c.writer.statusCode = &c.StatusCode
+	c.writer.reset(resp)
+	c.Response = &c.writer
+	c.Request = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = nil
+	c.fullPath = ""
+	c.handlers = nil
+	c.index = -1
+	c.Keys = nil
+	c.Errors = c.Errors[:0]
+	c.sameSite = http.SameSiteDefaultMode
+	c.funcMap = nil
+	c.busy = nil
 }
+
+var _ context.Context = (*Context)(nil)
@@ -0,0 +1,105 @@
+package geeweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextAbort(t *testing.T) {
+	e := New()
+	e.Use(func(ctx *Context) {
+		if ctx.Query("token") != "secret" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, H{"error": "unauthorized"})
+			return
+		}
+		ctx.Set("user", "geektutu")
+		ctx.Set("level", 3)
+	})
+	e.GET("/me", func(ctx *Context) {
+		ctx.String(http.StatusOK, "%s %d", ctx.GetString("user"), ctx.GetInt("level"))
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if w.Code != http.StatusUnauthorized || w.Body.String() != "{\"error\":\"unauthorized\"}\n" {
+		t.Fatalf("aborted request got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me?token=secret", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu 3" {
+		t.Fatalf("authorized request got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestContextKeysAreReset(t *testing.T) {
+	e := New()
+	e.GET("/set", func(ctx *Context) { ctx.Set("k", "v") })
+	e.GET("/get", func(ctx *Context) {
+		if _, ok := ctx.Get("k"); ok {
+			t.Fatal("keys leaked from a previous request")
+		}
+	})
+	for i := 0; i < 10; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/set", nil))
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/get", nil))
+	}
+}
+
+type ctxKey struct{}
+
+func TestContextAsContext(t *testing.T) {
+	e := New()
+	e.Use(func(ctx *Context) { ctx.Set("user", "from keys") })
+	e.GET("/", func(ctx *Context) {
+		var c context.Context = ctx
+		if c.Value(ctxKey{}) != "from request" || c.Value("user") != "from keys" {
+			t.Fatal("Value should look up Keys first and then the request context")
+		}
+		if c.Err() == nil {
+			t.Fatal("Err should follow the request context")
+		}
+	})
+
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "from request"))
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestContextCookie(t *testing.T) {
+	e := New()
+	e.GET("/", func(ctx *Context) {
+		value, err := ctx.Cookie("lang")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx.SetSameSite(http.SameSiteStrictMode)
+		ctx.SetCookie("greeting", value+" 你好", 3600, "", "", true, true)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "zh-CN"})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	expect := "greeting=zh-CN+%E4%BD%A0%E5%A5%BD; Path=/; Max-Age=3600; HttpOnly; Secure; SameSite=Strict"
+	if got := w.Header().Get("Set-Cookie"); got != expect {
+		t.Fatalf("expect %q, got %q", expect, got)
+	}
+}
+
+func TestContextStatusCode(t *testing.T) {
+	e := New()
+	var before, after int
+	e.Use(func(ctx *Context) {
+		before = ctx.StatusCode
+		ctx.Next()
+		after = ctx.StatusCode
+	})
+	e.GET("/", func(ctx *Context) { ctx.JSON(http.StatusCreated, H{}) })
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if before != http.StatusOK || after != http.StatusCreated {
+		t.Fatalf("StatusCode should follow the response, got %d then %d", before, after)
+	}
+}
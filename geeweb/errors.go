@@ -0,0 +1,171 @@
+package geeweb
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type ErrorType uint64
+
+const (
+	// ErrorTypeBind 由 Bind 绑定或校验失败产生，信息可以返回给客户端
+	ErrorTypeBind ErrorType = 1 << iota
+	// ErrorTypeRender 由渲染失败产生
+	ErrorTypeRender
+	// ErrorTypePrivate 只记录日志，不返回给客户端，是 Context.Error 的默认类型
+	ErrorTypePrivate
+	// ErrorTypePublic 信息可以返回给客户端
+	ErrorTypePublic
+
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+// Error 是处理过程中通过 Context.Error 收集的错误
+type Error struct {
+	Err  error
+	Type ErrorType
+	Meta interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) SetType(typ ErrorType) *Error {
+	e.Type = typ
+	return e
+}
+
+func (e *Error) SetMeta(meta interface{}) *Error {
+	e.Meta = meta
+	return e
+}
+
+func (e *Error) IsType(typ ErrorType) bool {
+	return e.Type&typ > 0
+}
+
+type errorMsgs []*Error
+
+// ByType 返回指定类型的错误，typ 可以是多个类型的或
+func (errs errorMsgs) ByType(typ ErrorType) errorMsgs {
+	if typ == ErrorTypeAny {
+		return errs
+	}
+	var result errorMsgs
+	for _, e := range errs {
+		if e.IsType(typ) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (errs errorMsgs) Last() *Error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+func (errs errorMsgs) String() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Error 记录一个错误并返回它，方便继续设置类型，处理链结束后由 Engine 的 ErrorHandler 统一输出
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("geeweb: err is nil")
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Err: err, Type: ErrorTypePrivate}
+	}
+	c.Errors = append(c.Errors, e)
+	return e
+}
+
+// AbortWithError 记录错误、设置状态码并终止处理链
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.AbortWithStatus(code)
+	return c.Error(err)
+}
+
+// ErrorHandler 设置处理链结束后、响应还没有写出且 Context.Errors 不为空时调用的函数
+func (e *Engine) ErrorHandler(handler HandlerFunc) {
+	e.errorHandler = handler
+}
+
+// defaultErrorHandler 只返回可公开的错误信息，其余错误返回状态码对应的文本
+func defaultErrorHandler(ctx *Context) {
+	code := ctx.Response.Status()
+	if code < http.StatusBadRequest {
+		code = http.StatusInternalServerError
+	}
+	message := http.StatusText(code)
+	if public := ctx.Errors.ByType(ErrorTypePublic | ErrorTypeBind); len(public) > 0 {
+		message = public.String()
+	}
+	ctx.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.Response.Header().Set("X-Content-Type-Options", "nosniff")
+	ctx.Response.WriteHeader(code)
+	_, _ = fmt.Fprintln(ctx.Response, message)
+}
+
+// NoRoute 设置分组下没有匹配路由时的处理函数，没有设置时使用父分组的
+func (g *RouterGroup) NoRoute(handlers ...HandlerFunc) {
+	g.noRoute = handlers
+}
+
+// NoMethod 设置分组下路由存在但方法不匹配时的处理函数，没有设置时使用父分组的
+func (g *RouterGroup) NoMethod(handlers ...HandlerFunc) {
+	g.noMethod = handlers
+}
+
+// groupFor 返回路由树中前缀与 path 按路径段匹配的最深的分组
+func (r *router) groupFor(path string) *RouterGroup {
+	best := r.groups[0]
+	for _, g := range r.groups {
+		prefix := strings.TrimSuffix(g.prefix, "/")
+		if len(prefix) <= len(strings.TrimSuffix(best.prefix, "/")) {
+			continue
+		}
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			best = g
+		}
+	}
+	return best
+}
+
+// missHandlers 沿父分组找到第一个设置了 NoRoute/NoMethod 的分组，使用该分组的中间件；
+// 都没有设置时只执行根分组的中间件，避免分组的中间件（比如鉴权）作用到不存在的路由上
+func (r *router) missHandlers(path string, methodNotAllowed bool) []HandlerFunc {
+	root := r.groups[0]
+	for group := r.groupFor(path); group != nil; group = group.parent {
+		handlers := group.noRoute
+		if methodNotAllowed {
+			handlers = group.noMethod
+		}
+		if len(handlers) == 0 {
+			continue
+		}
+		// Host 的路由树沿 parent 找到 Engine 上设置的处理函数时，依然使用 Host 根分组的中间件
+		if group.router != r {
+			group = root
+		}
+		return group.combineHandlers(handlers)
+	}
+	if methodNotAllowed {
+		return root.combineHandlers([]HandlerFunc{methodNotAllowedHandler})
+	}
+	return root.combineHandlers([]HandlerFunc{notFoundHandler})
+}
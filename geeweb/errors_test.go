@@ -0,0 +1,103 @@
+package geeweb
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandler(t *testing.T) {
+	e := New()
+	e.ErrorHandler(func(ctx *Context) {
+		public := ctx.Errors.ByType(ErrorTypePublic | ErrorTypeBind)
+		ctx.JSON(ctx.Response.Status(), H{"code": ctx.Response.Status(), "errors": len(ctx.Errors), "message": public.String()})
+	})
+	e.GET("/users/:id", func(ctx *Context) {
+		ctx.Error(errors.New("db: connection refused"))
+		ctx.AbortWithError(http.StatusNotFound, errors.New("user not found")).SetType(ErrorTypePublic)
+	})
+	e.POST("/users", func(ctx *Context) {
+		var u createUser
+		_ = ctx.Bind(&u)
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "{\"code\":404,\"errors\":2,\"message\":\"user not found\"}\n" {
+		t.Fatalf("unexpected error response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", MIMEJSON)
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "'required'") {
+		t.Fatalf("bind errors should be rendered by the error handler, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var out bytes.Buffer
+	e := New()
+	e.Use(RecoveryWithWriter(&out))
+	e.GET("/panic", func(ctx *Context) {
+		var arr []int
+		_ = arr[1]
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error\n" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(out.String(), "index out of range") {
+		t.Fatalf("stack should be written to the writer, got %q", out.String())
+	}
+
+	e = New()
+	e.Use(CustomRecovery(func(ctx *Context, err interface{}) {
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, H{"panic": err})
+	}))
+	e.GET("/panic", func(ctx *Context) { panic("boom") })
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "{\"panic\":\"boom\"}\n" {
+		t.Fatalf("unexpected custom recovery response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestGroupNoRouteAndNoMethod(t *testing.T) {
+	e := New()
+	e.NoRoute(func(ctx *Context) { ctx.String(http.StatusNotFound, "root 404") })
+	api := e.Group("/api")
+	api.Use(func(ctx *Context) { ctx.SetHeader("X-Group", "api") })
+	api.NoRoute(func(ctx *Context) { ctx.JSON(http.StatusNotFound, H{"error": "not found"}) })
+	api.NoMethod(func(ctx *Context) { ctx.JSON(http.StatusMethodNotAllowed, H{"error": "method not allowed"}) })
+	api.GET("/users", func(ctx *Context) {})
+
+	// 只有设置了 NoRoute/NoMethod 的分组的中间件会作用到未匹配的请求上
+	admin := e.Group("/admin")
+	admin.Use(func(ctx *Context) { ctx.SetHeader("X-Group", "admin") })
+	admin.GET("/users", func(ctx *Context) {})
+
+	cases := []struct {
+		method, path, body string
+		code               int
+		group              string
+	}{
+		{http.MethodGet, "/unknown", "root 404", 404, ""},
+		{http.MethodGet, "/apix", "root 404", 404, ""},
+		{http.MethodGet, "/api/unknown", "{\"error\":\"not found\"}\n", 404, "api"},
+		{http.MethodPost, "/api/users", "{\"error\":\"method not allowed\"}\n", 405, "api"},
+		{http.MethodGet, "/admin/unknown", "root 404", 404, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.code || w.Body.String() != c.body || w.Header().Get("X-Group") != c.group {
+			t.Fatalf("%s %s got %d %q", c.method, c.path, w.Code, w.Body.String())
+		}
+	}
+}
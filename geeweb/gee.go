@@ -1,28 +1,45 @@
 package geeweb
 
 import (
+	"fmt"
+	"gee/websocket"
 	"html/template"
-	"log"
+	"net"
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 )
 
 type HandlerFunc func(ctx *Context)
 
 type RouterGroup struct {
 	prefix     string
-	middleware []HandlerFunc // 这个暂时用不到
-	parent     *RouterGroup  // 如果 engine 全部存储的话，parent 就没什么作用了
+	middleware []HandlerFunc
+	parent     *RouterGroup // 注册路由时沿 parent 向上收集中间件
+	noRoute    []HandlerFunc
+	noMethod   []HandlerFunc
 	engine     *Engine
+	host       string  // Host 注册的主机名，默认路由树中为空
+	router     *router // 注册路由的路由树
 }
 
 type Engine struct {
 	*RouterGroup
 	router        *router
-	groups        []*RouterGroup
+	hosts         []*hostRoute
 	htmlTemplates *template.Template // for html render
+	htmlBase      *template.Template // 未执行过的副本，用于 Context.SetTemplateFunc 时克隆
 	funcMap       template.FuncMap   // for html render
+	pool          sync.Pool          // 复用 Context
+	upgrader      *websocket.Upgrader
+	errorHandler  HandlerFunc
+
+	trustedCIDRs []*net.IPNet
+
+	serverMu      sync.Mutex
+	server        *http.Server
+	shutdownHooks []func()
 }
 
 func (g *RouterGroup) createStaticHandler(relativPath string, fs http.FileSystem) HandlerFunc {
@@ -47,14 +64,15 @@ func (g *RouterGroup) Static(relativePath string, local string) {
 }
 
 func (g *RouterGroup) Group(prefix string) *RouterGroup {
-	engine := g.engine
 	newGroup := &RouterGroup{
 		prefix:     g.prefix + prefix,
 		middleware: make([]HandlerFunc, 0),
 		parent:     g,
 		engine:     g.engine,
+		host:       g.host,
+		router:     g.router,
 	}
-	engine.groups = append(engine.groups, newGroup)
+	g.router.groups = append(g.router.groups, newGroup)
 	return newGroup
 }
 
@@ -62,34 +80,133 @@ func (g *RouterGroup) Use(middleware ...HandlerFunc) {
 	g.middleware = append(g.middleware, middleware...)
 }
 
-func (g *RouterGroup) GET(pattern string, f HandlerFunc) {
-	pattern = g.prefix + pattern
-	g.engine.addRoute("GET", pattern, f)
+// anyMethods 是 Any 注册的全部方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+	http.MethodConnect, http.MethodTrace,
+}
+
+// groupHandlers 返回从根分组到当前分组依次注册的中间件
+func (g *RouterGroup) groupHandlers() []HandlerFunc {
+	if g.parent == nil {
+		return g.middleware
+	}
+	parent := g.parent.groupHandlers()
+	chain := make([]HandlerFunc, 0, len(parent)+len(g.middleware))
+	chain = append(chain, parent...)
+	return append(chain, g.middleware...)
+}
+
+// combineHandlers 在注册时就确定完整的处理链：父分组中间件 -> 子分组中间件 -> 路由中间件 -> handler
+// 因此 Use 需要在注册路由之前调用
+func (g *RouterGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
+	middleware := g.groupHandlers()
+	chain := make([]HandlerFunc, 0, len(middleware)+len(handlers))
+	chain = append(chain, middleware...)
+	return append(chain, handlers...)
 }
 
-func (g *RouterGroup) POST(pattern string, f HandlerFunc) {
+func (g *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) {
+	if len(handlers) == 0 {
+		panic("geeweb: there must be at least one handler")
+	}
 	pattern = g.prefix + pattern
-	g.engine.addRoute("POST", pattern, f)
+	g.router.addRoute(method, pattern, g.combineHandlers(handlers))
+}
+
+func (g *RouterGroup) GET(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodGet, pattern, handlers...)
+}
+
+func (g *RouterGroup) POST(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPost, pattern, handlers...)
+}
+
+func (g *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPut, pattern, handlers...)
+}
+
+func (g *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodPatch, pattern, handlers...)
+}
+
+func (g *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodDelete, pattern, handlers...)
+}
+
+// HEAD 一般不需要注册，未注册时会自动使用 GET 的 handler
+func (g *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodHead, pattern, handlers...)
+}
+
+// OPTIONS 未注册时会自动根据已注册的方法返回 Allow 头
+func (g *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) {
+	g.Handle(http.MethodOptions, pattern, handlers...)
+}
+
+func (g *RouterGroup) Any(pattern string, handlers ...HandlerFunc) {
+	for _, method := range anyMethods {
+		g.Handle(method, pattern, handlers...)
+	}
 }
 
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := newContext(w, r)
-	for _, group := range e.groups {
-		if strings.HasPrefix(r.URL.Path, group.prefix) {
-			ctx.handlers = append(ctx.handlers, group.middleware...)
-		}
+	ctx := e.pool.Get().(*Context)
+	ctx.reset(w, r)
+	router, hostParams := e.routerFor(r.Host)
+	router.handle(ctx, hostParams)
+	if len(ctx.Errors) > 0 && !ctx.Response.Written() {
+		e.errorHandler(ctx)
+	}
+	ctx.Response.WriteHeaderNow()
+	if busy := ctx.busy; busy != nil {
+		// 超时的 handler 可能还在使用 ctx，等它返回之后再复用，避免写到之后的请求中
+		go func() {
+			<-busy
+			e.pool.Put(ctx)
+		}()
+		return
 	}
-	ctx.engine = e
-	e.router.handle(ctx)
+	e.pool.Put(ctx)
 }
 
-func (e *Engine) addRoute(method string, pattern string, f HandlerFunc) {
-	e.router.addRoute(method, pattern, f)
+// SetTrustedProxies 设置可信代理的 IP 或 CIDR，ClientIP 只信任它们转发的 X-Forwarded-For
+func (e *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("geeweb: invalid trusted proxy %q", proxy)
+			}
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	e.trustedCIDRs = cidrs
+	return nil
 }
 
-func (e *Engine) Run(addr string) error {
-	log.Printf("Gee Start! Listen Request on %v", addr)
-	return http.ListenAndServe(addr, e)
+func (e *Engine) isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range e.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 // SetFuncMap set functions for render html templates
@@ -97,16 +214,36 @@ func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
 	e.funcMap = funcMap
 }
 
+// AddFuncMap 在已有的函数之外添加模板函数，需要在 LoadHTMLGlob 之前调用
+func (e *Engine) AddFuncMap(funcMap template.FuncMap) {
+	if e.funcMap == nil {
+		e.funcMap = make(template.FuncMap, len(funcMap))
+	}
+	for name, fn := range funcMap {
+		e.funcMap[name] = fn
+	}
+}
+
 func (engine *Engine) LoadHTMLGlob(pattern string) {
 	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+	// html/template 执行之后就不能再克隆，所以单独保留一份
+	engine.htmlBase = template.Must(engine.htmlTemplates.Clone())
 }
 
 func New() *Engine {
-	e := &Engine{router: newRouter()}
+	e := &Engine{
+		router:       newRouter(),
+		upgrader:     &websocket.Upgrader{},
+		errorHandler: defaultErrorHandler,
+	}
 	e.RouterGroup = &RouterGroup{
 		engine:     e,
 		middleware: make([]HandlerFunc, 0),
+		router:     e.router,
+	}
+	e.router.groups = []*RouterGroup{e.RouterGroup}
+	e.pool.New = func() interface{} {
+		return &Context{engine: e}
 	}
-	e.groups = []*RouterGroup{e.RouterGroup}
 	return e
 }
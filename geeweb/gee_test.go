@@ -0,0 +1,48 @@
+package geeweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func traceMiddleware(name string) HandlerFunc {
+	return func(ctx *Context) {
+		ctx.SetHeader("X-Trace", strings.TrimPrefix(ctx.Response.Header().Get("X-Trace")+","+name, ","))
+		ctx.Next()
+	}
+}
+
+func TestHandlerChain(t *testing.T) {
+	e := New()
+	e.Use(traceMiddleware("engine"))
+	v1 := e.Group("/v1")
+	v1.Use(traceMiddleware("v1"))
+	admin := v1.Group("/admin")
+	admin.Use(traceMiddleware("admin"))
+	admin.GET("/users", traceMiddleware("route"), func(ctx *Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	v10 := e.Group("/v10")
+	v10.GET("/users", func(ctx *Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	cases := []struct {
+		path  string
+		trace string
+	}{
+		{"/v1/admin/users", "engine,v1,admin,route"},
+		{"/v10/users", "engine"},
+		{"/v1/unknown", "engine"},
+		{"/v10/unknown", "engine"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if trace := w.Header().Get("X-Trace"); trace != c.trace {
+			t.Fatalf("%s should run %q, got %q", c.path, c.trace, trace)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package geewebtest
+
+import (
+	"fmt"
+	geeweb "gee"
+	"net/http"
+	"testing"
+)
+
+func newEngine() *geeweb.Engine {
+	e := geeweb.New()
+	e.Use(func(ctx *geeweb.Context) {
+		if ctx.Request.Header.Get("X-Token") == "bad" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	e.POST("/hello/:name", func(ctx *geeweb.Context) {
+		var body struct {
+			Age int `json:"age"`
+		}
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.SetCookie("visited", "1", 60, "/", "", false, true)
+		ctx.JSON(http.StatusOK, geeweb.H{
+			"name": ctx.Param("name"),
+			"age":  body.Age,
+			"tags": []string{ctx.Query("tag"), ctx.Request.Header.Get("X-Token")},
+		})
+	})
+	e.POST("/upload", func(ctx *geeweb.Context) {
+		_, header, err := ctx.Request.FormFile("file")
+		if err != nil {
+			ctx.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.String(http.StatusOK, "%s %s %d", ctx.PostForm("title"), header.Filename, header.Size)
+	})
+	e.GET("/events", func(ctx *geeweb.Context) {
+		_ = ctx.SSEvent("greeting", "hello\nworld")
+		_ = ctx.SSEvent("", geeweb.H{"n": 1})
+	})
+	return e
+}
+
+func TestFluent(t *testing.T) {
+	api := New(newEngine())
+	resp := api.POST("/hello/geektutu").
+		WithHeader("X-Token", "abc").
+		WithQuery("tag", "go").
+		WithJSON(geeweb.H{"age": 18}).
+		Expect(t).
+		Status(http.StatusOK).
+		Header("Content-Type", "application/json; charset=utf-8").
+		JSONPath("$.name", "geektutu").
+		JSONPath("$.age", 18).
+		JSONPath("$['tags'][1]", "abc").
+		JSON(geeweb.H{"name": "geektutu", "age": 18, "tags": []string{"go", "abc"}})
+	if c := resp.Cookie("visited"); c == nil || c.Value != "1" {
+		t.Fatalf("expect cookie visited=1, got %v", c)
+	}
+
+	api.POST("/hello/geektutu").WithHeader("X-Token", "bad").Expect(t).Status(http.StatusUnauthorized)
+	api.GET("/hello/geektutu").Expect(t).Status(http.StatusMethodNotAllowed)
+}
+
+func TestMultipartAndSSE(t *testing.T) {
+	api := New(newEngine())
+	api.POST("/upload").
+		WithMultipart(map[string]string{"title": "avatar"}, File{Field: "file", Name: "a.png", Content: []byte("png")}).
+		Expect(t).
+		Body("avatar a.png 3")
+
+	events := api.GET("/events").Expect(t).Header("Content-Type", "text/event-stream").Events()
+	if len(events) != 2 || events[0].Event != "greeting" || events[0].Data != "hello\nworld" || events[1].Data != `{"n":1}` {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+type recordT struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordT) Helper() {}
+
+func (r *recordT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestFailures(t *testing.T) {
+	rt := &recordT{TB: t}
+	New(newEngine()).POST("/hello/geektutu").WithJSON(geeweb.H{"age": 1}).Expect(rt).
+		Status(http.StatusCreated).
+		JSONPath("$.name", "other").
+		JSONPath("$.missing", 1).
+		JSONPath("$.tags[5]", "x")
+	if len(rt.errors) != 4 {
+		t.Fatalf("expect 4 failures, got %q", rt.errors)
+	}
+}
+
+func TestCreateTestContext(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/users/1?verbose=1", nil)
+	ctx, w := CreateTestContext(req)
+	ctx.Params = map[string]string{"id": "1"}
+	func(ctx *geeweb.Context) {
+		ctx.String(http.StatusOK, "%s %s", ctx.Param("id"), ctx.Query("verbose"))
+	}(ctx)
+	if w.Code != http.StatusOK || w.Body.String() != "1 1" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,176 @@
+package geewebtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	geeweb "gee"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Tester 在进程内把请求交给 Engine 处理，不需要监听端口
+type Tester struct {
+	engine *geeweb.Engine
+}
+
+func New(engine *geeweb.Engine) *Tester {
+	return &Tester{engine: engine}
+}
+
+func (t *Tester) GET(path string) *Request     { return t.Request(http.MethodGet, path) }
+func (t *Tester) POST(path string) *Request    { return t.Request(http.MethodPost, path) }
+func (t *Tester) PUT(path string) *Request     { return t.Request(http.MethodPut, path) }
+func (t *Tester) PATCH(path string) *Request   { return t.Request(http.MethodPatch, path) }
+func (t *Tester) DELETE(path string) *Request  { return t.Request(http.MethodDelete, path) }
+func (t *Tester) HEAD(path string) *Request    { return t.Request(http.MethodHead, path) }
+func (t *Tester) OPTIONS(path string) *Request { return t.Request(http.MethodOptions, path) }
+
+func (t *Tester) Request(method, path string) *Request {
+	return &Request{
+		engine: t.engine,
+		method: method,
+		path:   path,
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+}
+
+// File 是 WithMultipart 上传的文件
+type File struct {
+	Field   string
+	Name    string
+	Content []byte
+}
+
+// Request 用链式调用构造请求，构造过程中的错误在 Expect 时报告
+type Request struct {
+	engine  *geeweb.Engine
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    io.Reader
+	ctx     context.Context
+	err     error
+}
+
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Add(key, value)
+	return r
+}
+
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) WithCookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// WithCookies 带上之前响应设置的 cookie，用于测试登录之后的请求
+func (r *Request) WithCookies(cookies []*http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookies...)
+	return r
+}
+
+func (r *Request) WithBody(contentType string, body io.Reader) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+func (r *Request) WithJSON(v interface{}) *Request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.err = err
+	}
+	return r.WithBody(geeweb.MIMEJSON, bytes.NewReader(data))
+}
+
+func (r *Request) WithForm(form url.Values) *Request {
+	return r.WithBody("application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+}
+
+func (r *Request) WithMultipart(fields map[string]string, files ...File) *Request {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			r.err = err
+		}
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile(f.Field, f.Name)
+		if err == nil {
+			_, err = part.Write(f.Content)
+		}
+		if err != nil {
+			r.err = err
+		}
+	}
+	if err := w.Close(); err != nil {
+		r.err = err
+	}
+	return r.WithBody(w.FormDataContentType(), &buf)
+}
+
+// WithContext 设置请求的 context，取消它可以模拟客户端断开，结束流式响应
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Build 返回构造好的 *http.Request
+func (r *Request) Build() *http.Request {
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, target, r.body)
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	for _, c := range r.cookies {
+		req.AddCookie(c)
+	}
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+	return req
+}
+
+// Do 执行请求并返回原始的 ResponseRecorder
+func (r *Request) Do() *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.engine.ServeHTTP(w, r.Build())
+	return w
+}
+
+// Expect 执行请求并返回用于断言的 Response，断言失败时调用 t.Errorf
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	if r.err != nil {
+		t.Fatalf("geewebtest: build %s %s: %v", r.method, r.path, r.err)
+	}
+	return &Response{t: t, Recorder: r.Do()}
+}
+
+// CreateTestContext 返回绑定到 ResponseRecorder 的 Context，用于单独测试一个 handler
+func CreateTestContext(req *http.Request) (*geeweb.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	ctx, _ := geeweb.CreateTestContext(w, req)
+	return ctx, w
+}
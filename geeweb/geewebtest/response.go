@@ -0,0 +1,220 @@
+package geewebtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gee/render"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response 的断言方法都返回自身，可以继续链式调用
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+}
+
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Recorder.Code != code {
+		r.t.Errorf("expect status %d, got %d, body %q", code, r.Recorder.Code, r.Recorder.Body.String())
+	}
+	return r
+}
+
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Header().Get(key); got != value {
+		r.t.Errorf("expect header %s %q, got %q", key, value, got)
+	}
+	return r
+}
+
+func (r *Response) Body(body string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Body.String(); got != body {
+		r.t.Errorf("expect body %q, got %q", body, got)
+	}
+	return r
+}
+
+func (r *Response) BodyContains(s string) *Response {
+	r.t.Helper()
+	if got := r.Recorder.Body.String(); !strings.Contains(got, s) {
+		r.t.Errorf("expect body to contain %q, got %q", s, got)
+	}
+	return r
+}
+
+// JSON 比较整个响应体，expected 先编码再解码，所以结构体和 map 都可以使用
+func (r *Response) JSON(expected interface{}) *Response {
+	r.t.Helper()
+	got, err := r.decodeJSON()
+	if err != nil {
+		r.t.Errorf("%v", err)
+		return r
+	}
+	if want := normalize(expected); !reflect.DeepEqual(got, want) {
+		r.t.Errorf("expect JSON %v, got %v", want, got)
+	}
+	return r
+}
+
+// JSONPath 比较 path 处的值，path 形如 $.users[0].name，也可以写作 $['users'][0]['name']
+func (r *Response) JSONPath(path string, expected interface{}) *Response {
+	r.t.Helper()
+	doc, err := r.decodeJSON()
+	if err != nil {
+		r.t.Errorf("%v", err)
+		return r
+	}
+	got, err := lookup(doc, path)
+	if err != nil {
+		r.t.Errorf("%s: %v", path, err)
+		return r
+	}
+	if want := normalize(expected); !reflect.DeepEqual(got, want) {
+		r.t.Errorf("expect %s to be %v, got %v", path, want, got)
+	}
+	return r
+}
+
+// Cookie 返回响应设置的 cookie，不存在时断言失败并返回 nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	r.t.Helper()
+	for _, c := range r.Recorder.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	r.t.Errorf("expect cookie %q to be set", name)
+	return nil
+}
+
+// Cookies 返回响应设置的所有 cookie，可以传给下一个请求的 WithCookies
+func (r *Response) Cookies() []*http.Cookie {
+	return r.Recorder.Result().Cookies()
+}
+
+// Events 按 text/event-stream 格式解析响应体，Data 为字符串，多行数据用换行连接
+func (r *Response) Events() []render.SSEvent {
+	r.t.Helper()
+	var (
+		events []render.SSEvent
+		event  render.SSEvent
+		data   []string
+		seen   bool
+	)
+	scanner := bufio.NewScanner(strings.NewReader(r.Recorder.Body.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if seen {
+				event.Data = strings.Join(data, "\n")
+				events = append(events, event)
+			}
+			event, data, seen = render.SSEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		seen = true
+		switch field {
+		case "event":
+			event.Event = value
+		case "id":
+			event.ID = value
+		case "retry":
+			retry, _ := strconv.ParseUint(value, 10, 64)
+			event.Retry = uint(retry)
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return events
+}
+
+func (r *Response) decodeJSON() (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &v); err != nil {
+		return nil, fmt.Errorf("response is not JSON: %v, body %q", err, r.Recorder.Body.String())
+	}
+	return v, nil
+}
+
+// normalize 把任意值转换成 json.Unmarshal 到 interface{} 得到的形式，数字都是 float64
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var result interface{}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+func lookup(doc interface{}, path string) (interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must start with $")
+	}
+	rest := path[1:]
+	cur := doc
+	for rest != "" {
+		var key string
+		index := -1
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key, rest = rest[:end], rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [")
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') {
+				key = inner[1 : len(inner)-1]
+			} else {
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				index = i
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+
+		if index >= 0 {
+			arr, ok := cur.([]interface{})
+			if !ok || index >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range", index)
+			}
+			cur = arr[index]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%q is not an object", key)
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("key %q not found", key)
+		}
+	}
+	return cur, nil
+}
@@ -1,3 +1,10 @@
 module gee
 
 go 1.17
+
+require (
+	geetrace v0.0.0
+	google.golang.org/protobuf v1.28.0
+)
+
+replace geetrace => ../geetrace
@@ -0,0 +1,103 @@
+package geeweb
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostRoute 是 Host 注册的虚拟主机，每个主机有独立的路由树
+type hostRoute struct {
+	pattern string
+	labels  []string // 按 . 切分的 pattern，:name 匹配一个非空的标签
+	router  *router
+}
+
+// Host 返回 pattern 对应主机的根分组，pattern 可以是 api.example.com 这样的精确主机名，
+// 也可以是 :sub.example.com 这样带参数的主机名，参数可以通过 ctx.Param 读取。
+// 主机有独立的路由树、NoRoute 和中间件，Engine.Use 注册的中间件依然会执行，因此需要在 Host 之前调用。
+// 精确主机名优先于带参数的，带参数的按注册顺序匹配，都不匹配时使用默认的路由树
+func (e *Engine) Host(pattern string) *RouterGroup {
+	pattern = normalizeHost(pattern)
+	labels := strings.Split(pattern, ".")
+	for _, label := range labels {
+		if label == "" || label == ":" || strings.ContainsAny(label, "*/") {
+			panic(fmt.Sprintf("geeweb: invalid host pattern %q", pattern))
+		}
+	}
+	for _, h := range e.hosts {
+		if h.pattern == pattern {
+			return h.router.groups[0]
+		}
+	}
+	group := &RouterGroup{
+		middleware: make([]HandlerFunc, 0),
+		parent:     e.RouterGroup,
+		engine:     e,
+		host:       pattern,
+		router:     newRouter(),
+	}
+	group.router.groups = []*RouterGroup{group}
+	e.hosts = append(e.hosts, &hostRoute{pattern: pattern, labels: labels, router: group.router})
+	return group
+}
+
+// normalizeHost 去掉端口和结尾的 .，并转换为小写，端口只能是数字，避免把 :sub.example.com 当作端口
+func normalizeHost(host string) string {
+	if h, port, err := net.SplitHostPort(host); err == nil && isDigits(port) {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+func (h *hostRoute) match(labels []string) (map[string]string, bool) {
+	if len(labels) != len(h.labels) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, label := range h.labels {
+		if label[0] == ':' {
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[label[1:]] = labels[i]
+		} else if label != labels[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// routerFor 返回请求的主机对应的路由树和主机参数
+func (e *Engine) routerFor(host string) (*router, map[string]string) {
+	if len(e.hosts) == 0 {
+		return e.router, nil
+	}
+	host = normalizeHost(host)
+	for _, h := range e.hosts {
+		if h.pattern == host {
+			return h.router, nil
+		}
+	}
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if label == "" {
+			return e.router, nil
+		}
+	}
+	for _, h := range e.hosts {
+		if params, ok := h.match(labels); ok {
+			return h.router, params
+		}
+	}
+	return e.router, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,65 @@
+package geeweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	e := New()
+	e.Use(func(ctx *Context) { ctx.SetHeader("X-Global", "1") })
+	e.GET("/", func(ctx *Context) { ctx.String(http.StatusOK, "default") })
+
+	api := e.Host("api.example.com")
+	api.Use(func(ctx *Context) { ctx.SetHeader("X-Host", "api") })
+	api.GET("/users/:id", func(ctx *Context) { ctx.String(http.StatusOK, "api user %s", ctx.Param("id")) })
+	api.NoRoute(func(ctx *Context) { ctx.String(http.StatusNotFound, "api 404") })
+
+	tenant := e.Host(":tenant.example.com")
+	tenant.Group("/v1").GET("/", func(ctx *Context) {
+		ctx.String(http.StatusOK, "tenant %s", ctx.Param("tenant"))
+	})
+	if e.Host("API.example.com") != api {
+		t.Fatal("Host should return the same group for the same pattern")
+	}
+
+	cases := []struct {
+		host, path, body, header string
+	}{
+		{"api.example.com", "/users/42", "api user 42", "api"},
+		{"API.example.com:8080", "/users/42", "api user 42", "api"},
+		{"api.example.com", "/", "api 404", "api"},
+		{"acme.example.com", "/v1", "tenant acme", ""},
+		{"example.com", "/", "default", ""},
+		{"a.b.example.com", "/", "default", ""},
+		{"localhost:9999", "/", "default", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Host = c.host
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Body.String() != c.body || w.Header().Get("X-Host") != c.header || w.Header().Get("X-Global") != "1" {
+			t.Fatalf("%s%s: expect %q, got %q %v", c.host, c.path, c.body, w.Body.String(), w.Header())
+		}
+	}
+
+	routes := e.Routes()
+	if len(routes) != 3 || routes[0].Host != "" || routes[1].Host != ":tenant.example.com" || routes[2].Path != "/users/:id" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+}
+
+func TestHostInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"", "api..example.com", ":.example.com", "*.example.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Host(%q) should panic", pattern)
+				}
+			}()
+			New().Host(pattern)
+		}()
+	}
+}
@@ -0,0 +1,235 @@
+package geeweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogFormat int
+
+const (
+	LogFormatText     LogFormat = iota // 默认的单行文本格式
+	LogFormatCommon                    // Apache common log format
+	LogFormatCombined                  // Apache combined log format
+	LogFormatLogfmt
+	LogFormatJSON
+)
+
+// UserKey 是认证中间件保存用户身份的键，访问日志从这里读取用户
+const UserKey = "geeweb.user"
+
+// LogParams 是一次请求的访问日志内容
+type LogParams struct {
+	TimeStamp  time.Time
+	Latency    time.Duration
+	StatusCode int
+	ClientIP   string
+	Method     string
+	Path       string // 包括 query
+	Proto      string
+	BodySize   int
+	UserAgent  string
+	Referer    string
+	RequestID  string
+	User       string // Keys 中 UserKey 为字符串时记录
+	Slow       bool   // 处理时间超过 SlowThreshold
+	// ErrorMessage 是 Context.Errors 中不公开的错误
+	ErrorMessage string
+}
+
+func (p LogParams) level() string {
+	if p.Slow || p.StatusCode >= 500 {
+		return "warn"
+	}
+	return "info"
+}
+
+type LoggerConfig struct {
+	// Output 默认为 os.Stderr
+	Output io.Writer
+	Format LogFormat
+	// Formatter 不为空时忽略 Format，返回值应以换行结尾
+	Formatter func(params LogParams) string
+	// SkipPaths 中的路径不记录日志，比如健康检查
+	SkipPaths []string
+	// SlowThreshold 大于 0 时，处理时间超过它的请求会被标记为 warn
+	SlowThreshold time.Duration
+}
+
+func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+func LoggerWithWriter(out io.Writer) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Output: out})
+}
+
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	out := conf.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	formatter := conf.Formatter
+	if formatter == nil {
+		formatter = formatters[conf.Format]
+	}
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, p := range conf.SkipPaths {
+		skip[p] = true
+	}
+	var mu sync.Mutex
+
+	return func(ctx *Context) {
+		start := time.Now()
+		path := ctx.Request.URL.RequestURI()
+		ctx.Next()
+		if skip[ctx.Path] {
+			return
+		}
+		params := LogParams{
+			TimeStamp:  time.Now(),
+			StatusCode: ctx.Response.Status(),
+			ClientIP:   ctx.ClientIP(),
+			Method:     ctx.Method,
+			Path:       path,
+			Proto:      ctx.Request.Proto,
+			BodySize:   ctx.Response.Size(),
+			UserAgent:  ctx.Request.UserAgent(),
+			Referer:    ctx.Request.Referer(),
+			RequestID:  ctx.RequestID(),
+			User:       ctx.GetString(UserKey),
+
+			ErrorMessage: ctx.Errors.ByType(ErrorTypePrivate).String(),
+		}
+		params.Latency = params.TimeStamp.Sub(start)
+		params.Slow = conf.SlowThreshold > 0 && params.Latency > conf.SlowThreshold
+		line := formatter(params)
+		mu.Lock()
+		_, _ = io.WriteString(out, line)
+		mu.Unlock()
+	}
+}
+
+var formatters = map[LogFormat]func(LogParams) string{
+	LogFormatText:     textLogFormatter,
+	LogFormatCommon:   commonLogFormatter,
+	LogFormatCombined: combinedLogFormatter,
+	LogFormatLogfmt:   logfmtLogFormatter,
+	LogFormatJSON:     jsonLogFormatter,
+}
+
+func textLogFormatter(p LogParams) string {
+	slow := ""
+	if p.Slow {
+		slow = " | SLOW"
+	}
+	return fmt.Sprintf("[GEE] %s | %3d | %13v | %15s | %-7s %s%s\n",
+		p.TimeStamp.Format("2006/01/02 - 15:04:05"), p.StatusCode, p.Latency, p.ClientIP, p.Method, p.Path, slow)
+}
+
+func commonLogFormatter(p LogParams) string {
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s\n",
+		p.ClientIP, dashIfEmpty(p.User), p.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		p.Method, p.Path, p.Proto, p.StatusCode, bodySize(p.BodySize))
+}
+
+func combinedLogFormatter(p LogParams) string {
+	common := commonLogFormatter(p)
+	return fmt.Sprintf("%s %q %q\n", common[:len(common)-1], dashIfEmpty(p.Referer), dashIfEmpty(p.UserAgent))
+}
+
+func logfmtLogFormatter(p LogParams) string {
+	var b strings.Builder
+	pairs := []struct{ k, v string }{
+		{"time", p.TimeStamp.Format(time.RFC3339)},
+		{"level", p.level()},
+		{"method", p.Method},
+		{"path", p.Path},
+		{"status", strconv.Itoa(p.StatusCode)},
+		{"latency", p.Latency.String()},
+		{"ip", p.ClientIP},
+		{"size", strconv.Itoa(maxInt(p.BodySize, 0))},
+		{"ua", p.UserAgent},
+		{"request_id", p.RequestID},
+	}
+	for i, pair := range pairs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(pair.k + "=" + logfmtValue(pair.v))
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+type jsonLogLine struct {
+	Time      string  `json:"time"`
+	Level     string  `json:"level"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	Size      int     `json:"size"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	Referer   string  `json:"referer,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+	User      string  `json:"user,omitempty"`
+	Slow      bool    `json:"slow,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func jsonLogFormatter(p LogParams) string {
+	data, _ := json.Marshal(jsonLogLine{
+		Time:      p.TimeStamp.Format(time.RFC3339Nano),
+		Level:     p.level(),
+		Method:    p.Method,
+		Path:      p.Path,
+		Status:    p.StatusCode,
+		LatencyMs: float64(p.Latency) / float64(time.Millisecond),
+		ClientIP:  p.ClientIP,
+		Size:      maxInt(p.BodySize, 0),
+		UserAgent: p.UserAgent,
+		Referer:   p.Referer,
+		RequestID: p.RequestID,
+		User:      p.User,
+		Slow:      p.Slow,
+		Error:     p.ErrorMessage,
+	})
+	return string(data) + "\n"
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func bodySize(n int) string {
+	if n <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// logfmtValue 对空值和含有空格、引号或等号的值加引号
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \"=\t\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package geeweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoggerJSONWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	e := New()
+	e.Use(RequestID(), LoggerWithConfig(LoggerConfig{
+		Output:        &buf,
+		Format:        LogFormatJSON,
+		SkipPaths:     []string{"/healthz"},
+		SlowThreshold: 10 * time.Millisecond,
+	}))
+	e.GET("/slow", func(ctx *Context) {
+		time.Sleep(20 * time.Millisecond)
+		ctx.String(http.StatusOK, "ok")
+	})
+	e.GET("/healthz", func(ctx *Context) { ctx.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/slow?x=1", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	req.Header.Set("User-Agent", "gee-test")
+	e.ServeHTTP(w, req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if w.Header().Get("X-Request-ID") != "abc-123" {
+		t.Fatal("request id should be echoed")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("healthz should be skipped, got %q", buf.String())
+	}
+	var line jsonLogLine
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Path != "/slow?x=1" || line.Status != 200 || line.Size != 2 || line.RequestID != "abc-123" ||
+		line.UserAgent != "gee-test" || !line.Slow || line.Level != "warn" || line.ClientIP != "192.0.2.1" {
+		t.Fatalf("unexpected log line %+v", line)
+	}
+}
+
+func TestLogFormats(t *testing.T) {
+	p := LogParams{
+		TimeStamp:  time.Date(2022, 5, 1, 8, 0, 0, 0, time.UTC),
+		StatusCode: 404,
+		ClientIP:   "10.0.0.1",
+		Method:     "GET",
+		Path:       "/a b",
+		Proto:      "HTTP/1.1",
+		BodySize:   12,
+		UserAgent:  "curl/7.0",
+		RequestID:  "id1",
+	}
+	cases := map[LogFormat]string{
+		LogFormatCommon:   "10.0.0.1 - - [01/May/2022:08:00:00 +0000] \"GET /a b HTTP/1.1\" 404 12\n",
+		LogFormatCombined: "10.0.0.1 - - [01/May/2022:08:00:00 +0000] \"GET /a b HTTP/1.1\" 404 12 \"-\" \"curl/7.0\"\n",
+		LogFormatLogfmt:   "time=2022-05-01T08:00:00Z level=info method=GET path=\"/a b\" status=404 latency=0s ip=10.0.0.1 size=12 ua=curl/7.0 request_id=id1\n",
+	}
+	for format, expect := range cases {
+		if got := formatters[format](p); got != expect {
+			t.Fatalf("format %d: expect %q, got %q", format, expect, got)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	e := New()
+	var ip string
+	e.GET("/", func(ctx *Context) { ip = ctx.ClientIP() })
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2, 10.0.0.3")
+
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if ip != "10.0.0.2" {
+		t.Fatalf("untrusted proxy headers should be ignored, got %s", ip)
+	}
+	if err := e.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if ip != "2.2.2.2" {
+		t.Fatalf("expect the rightmost untrusted address, got %s", ip)
+	}
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	geeweb "gee"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UserKey 是认证通过后用户名或 API Key 对应身份在 Context 中的 key，与 geeweb.UserKey 相同，访问日志会记录它
+const UserKey = geeweb.UserKey
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+// unauthorized 设置 WWW-Authenticate 并交给 Engine 的错误处理函数返回 401
+func unauthorized(ctx *geeweb.Context, challenge string, err error) {
+	if challenge != "" {
+		ctx.Response.Header().Set("WWW-Authenticate", challenge)
+	}
+	_ = ctx.AbortWithError(http.StatusUnauthorized, err).SetType(geeweb.ErrorTypePublic)
+}
+
+// Accounts 是用户名到密码的映射
+type Accounts map[string]string
+
+// BasicAuth 使用 HTTP Basic 认证，realm 为 "Authorization Required"
+func BasicAuth(accounts Accounts) geeweb.HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+func BasicAuthForRealm(accounts Accounts, realm string) geeweb.HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	// 预先计算期望的头部，比较时使用常量时间，避免通过耗时猜测密码
+	expected := make(map[string]string, len(accounts))
+	for user, password := range accounts {
+		expected[user] = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+	return func(ctx *geeweb.Context) {
+		header := ctx.Request.Header.Get("Authorization")
+		for user, value := range expected {
+			if subtle.ConstantTimeCompare([]byte(header), []byte(value)) == 1 {
+				ctx.Set(UserKey, user)
+				return
+			}
+		}
+		unauthorized(ctx, challenge, ErrUnauthorized)
+	}
+}
+
+type APIKeyConfig struct {
+	// Header 是携带 API Key 的请求头，默认为 X-API-Key
+	Header string
+	// Query 不为空时也从查询参数中读取
+	Query string
+	// Validate 校验 key 并返回对应的身份，身份会保存到 Context 的 UserKey 中
+	Validate func(key string) (identity string, ok bool)
+}
+
+// APIKeys 返回校验固定 key 集合的 Validate，keys 是 key 到身份的映射
+func APIKeys(keys map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		for k, identity := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return identity, true
+			}
+		}
+		return "", false
+	}
+}
+
+func APIKey(config APIKeyConfig) geeweb.HandlerFunc {
+	if config.Validate == nil {
+		panic("auth: APIKeyConfig.Validate is required")
+	}
+	if config.Header == "" {
+		config.Header = "X-API-Key"
+	}
+	return func(ctx *geeweb.Context) {
+		key := ctx.Request.Header.Get(config.Header)
+		if key == "" && config.Query != "" {
+			key = ctx.Query(config.Query)
+		}
+		if key != "" {
+			if identity, ok := config.Validate(key); ok {
+				ctx.Set(UserKey, identity)
+				return
+			}
+		}
+		unauthorized(ctx, "", ErrUnauthorized)
+	}
+}
+
+// bearerToken 从 Authorization 头部取出 Bearer token
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	geeweb "gee"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ClaimsKey 是 JWT 校验通过后 Claims 在 Context 中的 key
+const ClaimsKey = "geeweb.claims"
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+var (
+	ErrTokenMalformed   = errors.New("token is malformed")
+	ErrTokenSignature   = errors.New("token signature is invalid")
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+	ErrTokenIssuer      = errors.New("token issuer is invalid")
+	ErrTokenAudience    = errors.New("token audience is invalid")
+)
+
+// Claims 是 JWT 的载荷，数字按 json.Number 解码
+type Claims map[string]interface{}
+
+// Subject 返回 sub，通常是用户 ID
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// time 读取 exp、nbf 这类以秒为单位的时间戳
+func (c Claims) time(name string) (time.Time, bool, error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false, ErrTokenMalformed
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, ErrTokenMalformed
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true, nil
+}
+
+// audience 的 aud 可以是字符串或字符串数组
+func (c Claims) audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		result := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// Keyfunc 根据算法和 kid 返回校验用的密钥，HS256 返回 []byte，RS256 返回 *rsa.PublicKey，
+// ES256 返回 *ecdsa.PublicKey，密钥轮换时按 kid 返回新旧密钥即可
+type Keyfunc func(alg, kid string) (interface{}, error)
+
+type JWTConfig struct {
+	Keyfunc Keyfunc
+	// Algorithms 是允许的算法，默认为 HS256、RS256 和 ES256
+	Algorithms []string
+	// Issuer 和 Audience 不为空时校验 iss 和 aud
+	Issuer   string
+	Audience string
+	// Leeway 是校验 exp 和 nbf 时允许的时钟误差
+	Leeway time.Duration
+	// Query 不为空时，没有 Authorization 头部的请求从查询参数读取 token，比如 WebSocket
+	Query string
+}
+
+// JWT 校验 Bearer token，通过后把 Claims 保存到 Context 的 ClaimsKey 中，sub 不为空时保存到 UserKey 中
+func JWT(config JWTConfig) geeweb.HandlerFunc {
+	if config.Keyfunc == nil {
+		panic("auth: JWTConfig.Keyfunc is required")
+	}
+	return func(ctx *geeweb.Context) {
+		token := bearerToken(ctx.Request)
+		if token == "" && config.Query != "" {
+			token = ctx.Query(config.Query)
+		}
+		if token == "" {
+			unauthorized(ctx, `Bearer realm="geeweb"`, ErrUnauthorized)
+			return
+		}
+		claims, err := ParseJWT(token, config)
+		if err != nil {
+			// 只把哨兵错误返回给客户端，Keyfunc 等返回的具体原因只记录为私有错误
+			public := publicJWTError(err)
+			if public != err {
+				ctx.Error(err)
+			}
+			unauthorized(ctx, `Bearer error="invalid_token"`, public)
+			return
+		}
+		ctx.Set(ClaimsKey, claims)
+		if sub := claims.Subject(); sub != "" {
+			ctx.Set(UserKey, sub)
+		}
+	}
+}
+
+var jwtErrors = []error{
+	ErrTokenMalformed, ErrTokenSignature, ErrTokenExpired,
+	ErrTokenNotValidYet, ErrTokenIssuer, ErrTokenAudience,
+}
+
+// publicJWTError 返回 err 对应的哨兵错误，都不匹配时返回 ErrUnauthorized
+func publicJWTError(err error) error {
+	for _, sentinel := range jwtErrors {
+		if errors.Is(err, sentinel) {
+			return sentinel
+		}
+	}
+	return ErrUnauthorized
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// ParseJWT 校验签名和标准声明，返回 Claims
+func ParseJWT(token string, config JWTConfig) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if !allowed(config.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("%w: algorithm %q is not allowed", ErrTokenSignature, header.Alg)
+	}
+	key, err := config.Keyfunc(header.Alg, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenSignature, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if err := verify(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if exp, ok, err := claims.time("exp"); err != nil {
+		return nil, err
+	} else if ok && !now.Before(exp.Add(config.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok, err := claims.time("nbf"); err != nil {
+		return nil, err
+	} else if ok && now.Add(config.Leeway).Before(nbf) {
+		return nil, ErrTokenNotValidYet
+	}
+	if config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != config.Issuer {
+			return nil, ErrTokenIssuer
+		}
+	}
+	if config.Audience != "" && !contains(claims.audience(), config.Audience) {
+		return nil, ErrTokenAudience
+	}
+	return claims, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return ErrTokenMalformed
+	}
+	return nil
+}
+
+func allowed(algorithms []string, alg string) bool {
+	if len(algorithms) == 0 {
+		algorithms = []string{HS256, RS256, ES256}
+	}
+	return contains(algorithms, alg)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// verify 同时检查密钥类型，避免用 RSA 公钥当作 HMAC 密钥这类算法混淆攻击
+func verify(alg string, key interface{}, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: HS256 requires a []byte key", ErrTokenSignature)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrTokenSignature
+		}
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 requires an *rsa.PublicKey", ErrTokenSignature)
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return ErrTokenSignature
+		}
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: ES256 requires an *ecdsa.PublicKey", ErrTokenSignature)
+		}
+		// ES256 的签名是定长的 r 和 s 拼接，而不是 ASN.1 编码
+		if len(signature) != 64 {
+			return ErrTokenSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrTokenSignature
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrTokenSignature, alg)
+	}
+	return nil
+}
+
+// SignJWT 生成 token，key 为 []byte、*rsa.PrivateKey 或 *ecdsa.PrivateKey，kid 可以为空
+func SignJWT(claims Claims, alg, kid string, key interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		if alg != HS256 {
+			return "", fmt.Errorf("auth: []byte key cannot sign %s", alg)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return "", fmt.Errorf("auth: RSA key cannot sign %s", alg)
+		}
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			return "", err
+		}
+	case *ecdsa.PrivateKey:
+		if alg != ES256 {
+			return "", fmt.Errorf("auth: ECDSA key cannot sign %s", alg)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return "", err
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	default:
+		return "", fmt.Errorf("auth: unsupported key type %T", key)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	geeweb "gee"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secrets := map[string][]byte{"old": []byte("old-secret"), "new": []byte("new-secret")}
+	config := JWTConfig{
+		Issuer:   "geeweb",
+		Audience: "api",
+		Keyfunc: func(alg, kid string) (interface{}, error) {
+			switch alg {
+			case RS256:
+				return &rsaKey.PublicKey, nil
+			case ES256:
+				return &ecKey.PublicKey, nil
+			}
+			if secret, ok := secrets[kid]; ok {
+				return secret, nil
+			}
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		},
+	}
+	now := time.Now().Unix()
+	valid := Claims{"sub": "42", "iss": "geeweb", "aud": []string{"web", "api"}, "exp": now + 60, "nbf": now - 60}
+
+	sign := func(claims Claims, alg, kid string, key interface{}) string {
+		token, err := SignJWT(claims, alg, kid, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	for _, token := range []string{
+		sign(valid, HS256, "old", secrets["old"]),
+		sign(valid, HS256, "new", secrets["new"]),
+		sign(valid, RS256, "", rsaKey),
+		sign(valid, ES256, "", ecKey),
+	} {
+		claims, err := ParseJWT(token, config)
+		if err != nil || claims.Subject() != "42" {
+			t.Fatalf("valid token rejected: %v", err)
+		}
+	}
+
+	with := func(k string, v interface{}) Claims {
+		c := Claims{}
+		for key, value := range valid {
+			c[key] = value
+		}
+		c[k] = v
+		return c
+	}
+	hsToken := sign(valid, HS256, "new", secrets["new"])
+	cases := []struct {
+		token string
+		err   error
+	}{
+		{sign(with("exp", now-10), HS256, "new", secrets["new"]), ErrTokenExpired},
+		{sign(with("nbf", now+600), HS256, "new", secrets["new"]), ErrTokenNotValidYet},
+		{sign(with("iss", "other"), HS256, "new", secrets["new"]), ErrTokenIssuer},
+		{sign(with("aud", "web"), HS256, "new", secrets["new"]), ErrTokenAudience},
+		{sign(valid, HS256, "unknown", []byte("x")), ErrTokenSignature},
+		{sign(valid, HS256, "new", []byte("forged")), ErrTokenSignature},
+		{hsToken[:len(hsToken)-2] + "AA", ErrTokenSignature},
+		{"a.b", ErrTokenMalformed},
+	}
+	for i, c := range cases {
+		if _, err := ParseJWT(c.token, config); !errors.Is(err, c.err) {
+			t.Fatalf("case %d: expect %v, got %v", i, c.err, err)
+		}
+	}
+
+	// 限制算法之后，其他算法签名的 token 被拒绝
+	config.Algorithms = []string{RS256}
+	if _, err := ParseJWT(hsToken, config); !errors.Is(err, ErrTokenSignature) {
+		t.Fatalf("HS256 should not be allowed, got %v", err)
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	e := geeweb.New()
+	api := e.Group("/api")
+	api.Use(JWT(JWTConfig{Keyfunc: func(alg, kid string) (interface{}, error) { return secret, nil }}))
+	api.GET("/me", func(ctx *geeweb.Context) {
+		ctx.String(http.StatusOK, ctx.MustGet(ClaimsKey).(Claims).Subject())
+	})
+	api.GET("/user", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, ctx.GetString(UserKey)) })
+
+	token, _ := SignJWT(Claims{"sub": "geektutu", "exp": time.Now().Add(time.Minute).Unix()}, HS256, "", secret)
+	w := serve(e, "/api/me", map[string]string{"Authorization": "Bearer " + token})
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("expect geektutu, got %d %q", w.Code, w.Body.String())
+	}
+	if w = serve(e, "/api/user", map[string]string{"Authorization": "Bearer " + token}); w.Body.String() != "geektutu" {
+		t.Fatalf("sub should be saved as the user, got %q", w.Body.String())
+	}
+	w = serve(e, "/api/me", nil)
+	if w.Code != http.StatusUnauthorized || !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+		t.Fatalf("expect 401 with Bearer challenge, got %d", w.Code)
+	}
+	w = serve(e, "/api/me", map[string]string{"Authorization": "Bearer " + token + "x"})
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token"` {
+		t.Fatalf("expect invalid_token, got %d", w.Code)
+	}
+}
+
+func TestJWTErrorIsNotLeaked(t *testing.T) {
+	var private string
+	e := geeweb.New()
+	e.Use(func(ctx *geeweb.Context) {
+		ctx.Next()
+		private = ctx.Errors.ByType(geeweb.ErrorTypePrivate).String()
+	})
+	e.Use(JWT(JWTConfig{Keyfunc: func(alg, kid string) (interface{}, error) {
+		return nil, fmt.Errorf("key %q not found in vault://internal/keys", kid)
+	}}))
+	e.GET("/me", func(ctx *geeweb.Context) {})
+
+	token, _ := SignJWT(Claims{"sub": "geektutu"}, HS256, "k1", []byte("secret"))
+	w := serve(e, "/me", map[string]string{"Authorization": "Bearer " + token})
+	if w.Code != http.StatusUnauthorized || strings.Contains(w.Body.String(), "vault") ||
+		!strings.Contains(w.Body.String(), ErrTokenSignature.Error()) {
+		t.Fatalf("expect only the sentinel error in the body, got %d %q", w.Code, w.Body.String())
+	}
+	if !strings.Contains(private, "vault") {
+		t.Fatalf("the detail should be kept as a private error, got %q", private)
+	}
+}
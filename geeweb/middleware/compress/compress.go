@@ -0,0 +1,210 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	geeweb "gee"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Config 配置压缩中间件，零值字段使用默认值
+type Config struct {
+	// Level 是压缩等级，默认为 gzip.DefaultCompression
+	Level int
+	// MinLength 是开始压缩的最小响应体长度，默认 1024 字节，更小的响应压缩之后反而可能变大
+	MinLength int
+	// ExcludedContentTypes 中的类型不压缩，以 "/" 结尾表示整个大类，默认排除图片、音视频和压缩包
+	ExcludedContentTypes []string
+	// MaxRequestSize 是解压后请求体的最大长度，默认 32MB，防止压缩炸弹
+	MaxRequestSize int64
+}
+
+var defaultExcluded = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+	"application/octet-stream", "font/woff", "font/woff2",
+}
+
+const (
+	defaultMinLength      = 1024
+	defaultMaxRequestSize = 32 << 20
+)
+
+// Default 使用默认配置
+func Default() geeweb.HandlerFunc {
+	return New(Config{Level: gzip.DefaultCompression})
+}
+
+type compressor struct {
+	level          int
+	minLength      int
+	excluded       []string
+	maxRequestSize int64
+	gzipPool       sync.Pool
+	zlibPool       sync.Pool
+}
+
+// New 返回压缩中间件，它根据 Accept-Encoding 压缩响应，并解压 Content-Encoding 为 gzip 或 deflate 的请求体
+func New(config Config) geeweb.HandlerFunc {
+	c := &compressor{
+		level:          config.Level,
+		minLength:      config.MinLength,
+		excluded:       config.ExcludedContentTypes,
+		maxRequestSize: config.MaxRequestSize,
+	}
+	if c.level == 0 {
+		c.level = gzip.DefaultCompression
+	}
+	if c.level < gzip.HuffmanOnly || c.level > gzip.BestCompression {
+		panic("compress: invalid compression level " + strconv.Itoa(c.level))
+	}
+	if c.minLength <= 0 {
+		c.minLength = defaultMinLength
+	}
+	if c.excluded == nil {
+		c.excluded = defaultExcluded
+	}
+	if c.maxRequestSize <= 0 {
+		c.maxRequestSize = defaultMaxRequestSize
+	}
+	c.gzipPool.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, c.level)
+		return w
+	}
+	c.zlibPool.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, c.level)
+		return w
+	}
+	return c.handle
+}
+
+func (c *compressor) handle(ctx *geeweb.Context) {
+	if !c.decompressRequest(ctx) {
+		return
+	}
+	encoding := negotiate(ctx.Request.Header.Get("Accept-Encoding"))
+	ctx.Response.Header().Add("Vary", "Accept-Encoding")
+	if encoding == "" || ctx.Request.Method == http.MethodHead || isUpgrade(ctx.Request) {
+		ctx.Next()
+		return
+	}
+
+	w := &writer{ResponseWriter: ctx.Response, c: c, encoding: encoding}
+	ctx.Response = w
+	defer func() {
+		w.close()
+		ctx.Response = w.ResponseWriter
+	}()
+	ctx.Next()
+}
+
+// decompressRequest 把压缩的请求体替换为解压后的数据，绑定时读到的就是原始内容
+func (c *compressor) decompressRequest(ctx *geeweb.Context) bool {
+	req := ctx.Request
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+	if req.Body == nil || req.Body == http.NoBody || (encoding != "gzip" && encoding != "deflate") {
+		return true
+	}
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if encoding == "gzip" {
+		r, err = gzip.NewReader(req.Body)
+	} else {
+		r, err = zlib.NewReader(req.Body)
+	}
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusBadRequest, err)
+		return false
+	}
+	req.Body = &decompressedBody{
+		Reader: http.MaxBytesReader(ctx.Response, r, c.maxRequestSize),
+		closer: []io.Closer{r, req.Body},
+	}
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	return true
+}
+
+type decompressedBody struct {
+	io.Reader
+	closer []io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	var err error
+	for _, c := range b.closer {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// negotiate 按 q 值选择编码，同样可接受时优先 gzip，q=0 表示明确拒绝
+func negotiate(accept string) string {
+	if accept == "" {
+		return ""
+	}
+	q := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := mime.ParseMediaType(strings.TrimSpace(part))
+		if name == "" {
+			// 参数格式不合法时只取名字，权重按 1 处理
+			name = strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		}
+		weight := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				weight = f
+			}
+		}
+		q[name] = weight
+	}
+	pick := func(name string) float64 {
+		if w, ok := q[name]; ok {
+			return w
+		}
+		if w, ok := q["*"]; ok {
+			return w
+		}
+		return 0
+	}
+	gz, deflate := pick("gzip"), pick("deflate")
+	switch {
+	case gz > 0 && gz >= deflate:
+		return "gzip"
+	case deflate > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+func isUpgrade(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+func (c *compressor) compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	for _, excluded := range c.excluded {
+		if strings.HasSuffix(excluded, "/") {
+			if strings.HasPrefix(contentType, excluded) {
+				return false
+			}
+		} else if contentType == excluded {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	geeweb "gee"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var large = strings.Repeat("geeweb ", 1000)
+
+func newEngine() *geeweb.Engine {
+	e := geeweb.New()
+	e.Use(Default())
+	e.GET("/large", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, large) })
+	e.GET("/small", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "small") })
+	e.GET("/image", func(ctx *geeweb.Context) {
+		ctx.DataFromReader(http.StatusOK, -1, "image/png", strings.NewReader(large), nil)
+	})
+	e.GET("/stream", func(ctx *geeweb.Context) {
+		ctx.SetHeader("Content-Type", "text/plain")
+		_, _ = ctx.Response.Write([]byte("first"))
+		ctx.Flush()
+		_, _ = ctx.Response.Write([]byte("second"))
+	})
+	e.POST("/echo", func(ctx *geeweb.Context) {
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.Fail(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		ctx.Data(http.StatusOK, body)
+	})
+	return e
+}
+
+func do(e *geeweb.Engine, method, path, acceptEncoding string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressResponse(t *testing.T) {
+	e := newEngine()
+	w := do(e, "GET", "/large", "gzip, deflate", nil)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	if w.Body.Len() >= len(large) {
+		t.Fatal("body should be compressed")
+	}
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != large {
+		t.Fatal("failed to decompress body")
+	}
+
+	w = do(e, "GET", "/large", "gzip;q=0.5, deflate", nil)
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("deflate should be preferred, got %q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != large {
+		t.Fatal("failed to inflate body")
+	}
+}
+
+func TestSkipCompress(t *testing.T) {
+	e := newEngine()
+	cases := []struct{ path, accept, body string }{
+		{"/large", "", large},
+		{"/large", "gzip;q=0, identity", large},
+		{"/small", "gzip", "small"},
+		{"/image", "gzip", large},
+	}
+	for _, c := range cases {
+		w := do(e, "GET", c.path, c.accept, nil)
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != c.body {
+			t.Fatalf("%s with %q should not be compressed", c.path, c.accept)
+		}
+	}
+}
+
+func TestCompressStream(t *testing.T) {
+	e := newEngine()
+	w := do(e, "GET", "/stream", "gzip", nil)
+	if !w.Flushed || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("stream should be flushed and compressed, headers %v", w.Header())
+	}
+	r, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "firstsecond" {
+		t.Fatalf("unexpected body %q", data)
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	e := newEngine()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte(`{"name":"geektutu"}`))
+	_ = zw.Close()
+
+	req := httptest.NewRequest("POST", "/echo", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != `{"name":"geektutu"}` {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid gzip body should return 400, got %d", w.Code)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                     "",
+		"gzip":                 "gzip",
+		"deflate":              "deflate",
+		"*":                    "gzip",
+		"br, deflate":          "deflate",
+		"gzip;q=0.1, deflate":  "deflate",
+		"*;q=0, identity":      "",
+		"GZIP;q=0.8, br;q=1.0": "gzip",
+	}
+	for accept, expect := range cases {
+		if got := negotiate(accept); got != expect {
+			t.Fatalf("negotiate(%q) = %q, expect %q", accept, got, expect)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	geeweb "gee"
+	"io"
+	"net/http"
+)
+
+// resetWriter 是 gzip.Writer 和 zlib.Writer 的公共方法
+type resetWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// writer 先缓冲响应体，达到 MinLength 或者 Flush 时才决定是否压缩
+type writer struct {
+	geeweb.ResponseWriter
+	c        *compressor
+	encoding string
+	buf      []byte
+	decided  bool
+	zw       resetWriter
+}
+
+var _ geeweb.ResponseWriter = (*writer)(nil)
+
+// decide 决定是否压缩并写出缓冲的数据，之后的写入直接进入压缩器或底层 ResponseWriter
+func (w *writer) decide(compress bool) {
+	w.decided = true
+	status := w.ResponseWriter.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		compress = false
+	}
+	if compress && w.c.compressible(w.Header()) {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+		if w.encoding == "gzip" {
+			w.zw = w.c.gzipPool.Get().(*gzip.Writer)
+		} else {
+			w.zw = w.c.zlibPool.Get().(*zlib.Writer)
+		}
+		w.zw.Reset(w.ResponseWriter)
+	}
+	if len(w.buf) > 0 {
+		buf := w.buf
+		w.buf = nil
+		_, _ = w.write(buf)
+	}
+}
+
+func (w *writer) write(data []byte) (int, error) {
+	if w.zw != nil {
+		w.ResponseWriter.WriteHeaderNow()
+		return w.zw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *writer) Write(data []byte) (int, error) {
+	if w.decided {
+		return w.write(data)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(append(w.buf, data...)))
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.c.minLength {
+		w.decide(true)
+	}
+	return len(data), nil
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *writer) ReadFrom(r io.Reader) (int64, error) {
+	// 隐藏 ReadFrom 方法，避免 io.Copy 再次调用自己
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+// Written 在数据还在缓冲区时也返回 true，避免错误处理覆盖已经写入的响应
+func (w *writer) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow 和 Flush 都意味着调用者要求立即发送，此时按内容类型决定是否压缩
+func (w *writer) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(true)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *writer) Flush() {
+	w.WriteHeaderNow()
+	if w.zw != nil {
+		_ = w.zw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close 在处理链结束后调用，未达到 MinLength 的响应原样写出
+func (w *writer) close() {
+	if !w.decided {
+		if len(w.buf) == 0 {
+			// 没有响应体时不修改头部，由 Engine 写出状态码
+			return
+		}
+		w.decide(false)
+	}
+	if w.zw == nil {
+		return
+	}
+	_ = w.zw.Close()
+	w.zw.Reset(io.Discard)
+	if w.encoding == "gzip" {
+		w.c.gzipPool.Put(w.zw)
+	} else {
+		w.c.zlibPool.Put(w.zw)
+	}
+	w.zw = nil
+}
@@ -0,0 +1,161 @@
+package cors
+
+import (
+	geeweb "gee"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config 配置跨域规则，AllowOrigins 支持精确匹配、"*" 和 "https://*.example.com" 这样的子域名通配
+type Config struct {
+	AllowOrigins []string
+	// AllowOriginFunc 不为空时，AllowOrigins 都不匹配的 Origin 交给它判断
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 默认为 GET、POST、PUT、PATCH、DELETE 和 HEAD
+	AllowMethods []string
+	// AllowHeaders 为空或包含 "*" 时允许预检请求中列出的所有头部
+	AllowHeaders  []string
+	ExposeHeaders []string
+	// AllowCredentials 不能和 AllowOrigins 中的 "*" 同时使用，需要列出允许的来源或者使用 AllowOriginFunc
+	AllowCredentials bool
+	// MaxAge 是浏览器缓存预检结果的时间，0 表示不设置
+	MaxAge time.Duration
+}
+
+var defaultMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodHead,
+}
+
+// Default 允许所有来源，不允许携带凭证
+func Default() geeweb.HandlerFunc {
+	return New(Config{AllowOrigins: []string{"*"}})
+}
+
+type cors struct {
+	allowAll         bool
+	exact            map[string]bool
+	wildcards        [][2]string // 通配符 * 两侧的前缀和后缀
+	allowOriginFunc  func(origin string) bool
+	allowMethods     string
+	allowHeaders     string
+	allowAnyHeader   bool
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// New 返回跨域中间件，可以通过 RouterGroup.Use 只作用于某个分组
+// 预检请求由中间件直接返回 204，不需要注册 OPTIONS 路由，来源不允许时返回 403
+func New(config Config) geeweb.HandlerFunc {
+	for _, origin := range config.AllowOrigins {
+		// 允许任意来源携带凭证时，任何网站都可以读取用户登录后的响应
+		if origin == "*" && config.AllowCredentials {
+			panic("cors: AllowOrigins \"*\" cannot be used with AllowCredentials")
+		}
+	}
+	c := &cors{
+		exact:            make(map[string]bool),
+		allowOriginFunc:  config.AllowOriginFunc,
+		allowCredentials: config.AllowCredentials,
+		exposeHeaders:    strings.Join(config.ExposeHeaders, ", "),
+	}
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			c.allowAll = true
+		case i >= 0:
+			c.wildcards = append(c.wildcards, [2]string{origin[:i], origin[i+1:]})
+		default:
+			c.exact[origin] = true
+		}
+	}
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+	c.allowMethods = strings.ToUpper(strings.Join(methods, ", "))
+	for _, h := range config.AllowHeaders {
+		if h == "*" {
+			c.allowAnyHeader = true
+		}
+	}
+	c.allowAnyHeader = c.allowAnyHeader || len(config.AllowHeaders) == 0
+	c.allowHeaders = strings.Join(config.AllowHeaders, ", ")
+	if config.MaxAge > 0 {
+		c.maxAge = strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	}
+	return c.handle
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if c.exact[lower] {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	return c.allowOriginFunc != nil && c.allowOriginFunc(origin)
+}
+
+func (c *cors) handle(ctx *geeweb.Context) {
+	origin := ctx.Request.Header.Get("Origin")
+	if origin == "" {
+		ctx.Next()
+		return
+	}
+	header := ctx.Response.Header()
+	header.Add("Vary", "Origin")
+	preflight := ctx.Method == http.MethodOptions && ctx.Request.Header.Get("Access-Control-Request-Method") != ""
+	if !c.isOriginAllowed(origin) {
+		// 浏览器在同源的 POST 等请求中也会带上 Origin，简单请求不加 CORS 头部照常处理，由浏览器拦截跨域的响应
+		if preflight {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		ctx.Next()
+		return
+	}
+
+	if c.allowAll {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !preflight {
+		if c.exposeHeaders != "" {
+			header.Set("Access-Control-Expose-Headers", c.exposeHeaders)
+		}
+		ctx.Next()
+		return
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", c.allowMethods)
+	if c.allowAnyHeader {
+		if requested := ctx.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+	} else {
+		header.Set("Access-Control-Allow-Headers", c.allowHeaders)
+	}
+	if c.maxAge != "" {
+		header.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	header.Del("Allow")
+	ctx.AbortWithStatus(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package cors
+
+import (
+	geeweb "gee"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newEngine() *geeweb.Engine {
+	e := geeweb.New()
+	e.GET("/public", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "public") })
+	api := e.Group("/api")
+	api.Use(New(Config{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return origin == "http://localhost:3000" },
+		AllowMethods:     []string{"GET", "PUT"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	api.GET("/users", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "users") })
+	return e
+}
+
+func TestPreflightWithoutOptionsRoute(t *testing.T) {
+	e := newEngine()
+	req := httptest.NewRequest(http.MethodOptions, "/api/users", nil)
+	req.Header.Set("Origin", "https://dev.example.org")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	expect := map[string]string{
+		"Access-Control-Allow-Origin":      "https://dev.example.org",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight should return 204, got %d", w.Code)
+	}
+	for k, v := range expect {
+		if w.Header().Get(k) != v {
+			t.Fatalf("%s should be %q, got %q", k, v, w.Header().Get(k))
+		}
+	}
+}
+
+func TestPreflightRejected(t *testing.T) {
+	e := newEngine()
+	req := httptest.NewRequest(http.MethodOptions, "/api/users", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("preflight from a disallowed origin should be 403, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestActualRequest(t *testing.T) {
+	e := newEngine()
+	cases := []struct {
+		path, origin string
+		code         int
+		allowOrigin  string
+	}{
+		{"/api/users", "https://app.example.com", http.StatusOK, "https://app.example.com"},
+		{"/api/users", "http://localhost:3000", http.StatusOK, "http://localhost:3000"},
+		// 不允许的来源照常处理，但没有 CORS 头部，浏览器不会把响应交给跨域的页面
+		{"/api/users", "https://example.org", http.StatusOK, ""},
+		{"/api/users", "https://evil.com", http.StatusOK, ""},
+		{"/api/users", "", http.StatusOK, ""},
+		// 其他分组不受影响
+		{"/public", "https://evil.com", http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != c.code || w.Header().Get("Access-Control-Allow-Origin") != c.allowOrigin {
+			t.Fatalf("%s from %q got %d, allow origin %q", c.path, c.origin, w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if c.code == http.StatusOK && c.allowOrigin != "" && w.Header().Get("Access-Control-Expose-Headers") != "X-Total" {
+			t.Fatal("expose headers should be set on actual requests")
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	e := geeweb.New()
+	e.Use(Default())
+	e.GET("/", func(ctx *geeweb.Context) {})
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://any.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "X-Custom" {
+		t.Fatalf("unexpected preflight response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestWildcardWithCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("\"*\" with AllowCredentials should panic")
+		}
+	}()
+	New(Config{AllowOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true})
+}
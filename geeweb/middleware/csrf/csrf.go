@@ -0,0 +1,222 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	geeweb "gee"
+	"gee/sessions"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// TokenKey 是本次请求的 token 在 Context 中的 key
+const TokenKey = "geeweb.csrf"
+
+const (
+	tokenLength = 32
+	sessionKey  = "_csrf"
+)
+
+var ErrInvalidToken = errors.New("csrf token is missing or invalid")
+
+type Config struct {
+	// Secret 用于签名双重提交的 Cookie，防止子域名写入伪造的 Cookie，UseSession 为 true 时不需要
+	Secret []byte
+	// UseSession 为 true 时 token 保存在 sessions 中间件的会话里，否则使用双重提交 Cookie
+	UseSession bool
+	// CookieName 默认为 _csrf，Secure 为 true 时 Cookie 只通过 HTTPS 发送
+	CookieName string
+	Secure     bool
+	// FieldName 是表单字段名，默认为 csrf_token；HeaderName 默认为 X-CSRF-Token，用于 AJAX 请求
+	FieldName  string
+	HeaderName string
+	// Exempt 中的路径不检查 token，以 * 结尾时按前缀匹配，比如第三方回调
+	Exempt []string
+}
+
+// FuncMap 返回模板中使用的 csrfField 和 csrfToken，需要在 LoadHTMLGlob 之前通过 Engine.AddFuncMap 注册，
+// 中间件会为每个请求替换成返回当前 token 的函数
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrfField": func() template.HTML { return "" },
+		"csrfToken": func() string { return "" },
+	}
+}
+
+// Token 返回本次请求的 token，可以放到 JSON 响应或者 meta 标签中
+func Token(ctx *geeweb.Context) string {
+	return ctx.GetString(TokenKey)
+}
+
+type csrf struct {
+	Config
+}
+
+// New 返回 CSRF 中间件，安全方法的请求只签发 token，其他方法的请求校验表单字段或请求头中的 token，失败时返回 403
+func New(config Config) geeweb.HandlerFunc {
+	if !config.UseSession && len(config.Secret) == 0 {
+		panic("csrf: Secret is required when UseSession is false")
+	}
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "csrf_token"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	c := &csrf{config}
+	return c.handle
+}
+
+func (c *csrf) handle(ctx *geeweb.Context) {
+	token, err := c.load(ctx)
+	if err != nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if !safeMethod(ctx.Method) && !c.exempt(ctx.Path) {
+		sent := ctx.Request.Header.Get(c.HeaderName)
+		if sent == "" {
+			sent = ctx.PostForm(c.FieldName)
+		}
+		if token == nil || !validate(sent, token) {
+			_ = ctx.AbortWithError(http.StatusForbidden, ErrInvalidToken).SetType(geeweb.ErrorTypePublic)
+			return
+		}
+	}
+
+	// 每次输出都使用不同的掩码，防止 BREACH 攻击从压缩后的长度推测出 token
+	masked := mask(token)
+	ctx.Set(TokenKey, masked)
+	ctx.Response.Header().Add("Vary", "Cookie")
+	ctx.SetTemplateFunc("csrfToken", func() string { return masked })
+	ctx.SetTemplateFunc("csrfField", func() template.HTML {
+		return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+			template.HTMLEscapeString(c.FieldName), masked))
+	})
+}
+
+// load 返回已经签发的 token，没有时生成新的 token 并保存
+func (c *csrf) load(ctx *geeweb.Context) ([]byte, error) {
+	if c.UseSession {
+		session := sessions.Default(ctx)
+		if s, ok := session.Get(sessionKey).(string); ok {
+			if token, err := base64.RawURLEncoding.DecodeString(s); err == nil && len(token) == tokenLength {
+				return token, nil
+			}
+		}
+		token, err := randomBytes(tokenLength)
+		if err != nil {
+			return nil, err
+		}
+		session.Set(sessionKey, base64.RawURLEncoding.EncodeToString(token))
+		return token, session.Save()
+	}
+
+	if value, err := ctx.Cookie(c.CookieName); err == nil {
+		if token := c.verify(value); token != nil {
+			return token, nil
+		}
+	}
+	token, err := randomBytes(tokenLength)
+	if err != nil {
+		return nil, err
+	}
+	// 浏览器端的脚本通过 csrfToken 获取 token，Cookie 不需要被脚本读取
+	// 直接构造 Cookie，不修改 Context 中之后其他 Cookie 使用的 SameSite
+	http.SetCookie(ctx.Response, &http.Cookie{
+		Name:     c.CookieName,
+		Value:    c.sign(token),
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		Secure:   c.Secure,
+		HttpOnly: true,
+	})
+	return token, nil
+}
+
+// sign 返回 token.签名，签名保证 Cookie 是服务端签发的
+func (c *csrf) sign(token []byte) string {
+	h := hmac.New(sha256.New, c.Secret)
+	h.Write(token)
+	return base64.RawURLEncoding.EncodeToString(token) + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (c *csrf) verify(value string) []byte {
+	i := strings.IndexByte(value, '.')
+	if i < 0 {
+		return nil
+	}
+	token, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil || len(token) != tokenLength {
+		return nil
+	}
+	if !hmac.Equal([]byte(value), []byte(c.sign(token))) {
+		return nil
+	}
+	return token
+}
+
+func (c *csrf) exempt(path string) bool {
+	for _, e := range c.Exempt {
+		if strings.HasSuffix(e, "*") {
+			if strings.HasPrefix(path, e[:len(e)-1]) {
+				return true
+			}
+		} else if path == e {
+			return true
+		}
+	}
+	return false
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// mask 返回 base64(pad + (pad xor token))
+func mask(token []byte) string {
+	pad, err := randomBytes(len(token))
+	if err != nil {
+		// 随机数不可用时不使用掩码，token 本身依然有效
+		pad = make([]byte, len(token))
+	}
+	out := make([]byte, 2*len(token))
+	copy(out, pad)
+	for i := range token {
+		out[len(token)+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+func validate(sent string, token []byte) bool {
+	data, err := base64.RawURLEncoding.DecodeString(sent)
+	if err != nil || len(data) != 2*len(token) {
+		return false
+	}
+	unmasked := make([]byte, len(token))
+	for i := range unmasked {
+		unmasked[i] = data[i] ^ data[len(token)+i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,140 @@
+package csrf
+
+import (
+	geeweb "gee"
+	"gee/sessions"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var fieldPattern = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="([\w-]+)">`)
+
+func newEngine(t *testing.T, config Config, middleware ...geeweb.HandlerFunc) *geeweb.Engine {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "form.tmpl"), []byte(`<form>{{csrfField}}</form>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := geeweb.New()
+	e.AddFuncMap(FuncMap())
+	e.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	e.Use(middleware...)
+	e.Use(New(config))
+	e.GET("/form", func(ctx *geeweb.Context) { ctx.HTML(http.StatusOK, "form.tmpl", nil) })
+	e.POST("/form", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "ok") })
+	e.POST("/webhook/github", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "ok") })
+	return e
+}
+
+// getForm 返回表单中的 token 和响应设置的 cookie
+func getForm(t *testing.T, e *geeweb.Engine, cookies []*http.Cookie) (string, []*http.Cookie) {
+	req := httptest.NewRequest("GET", "/form", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	match := fieldPattern.FindStringSubmatch(w.Body.String())
+	if match == nil {
+		t.Fatalf("form should contain csrf field, got %q", w.Body.String())
+	}
+	if set := w.Result().Cookies(); len(set) > 0 {
+		cookies = set
+	}
+	return match[1], cookies
+}
+
+func post(e *geeweb.Engine, path, token string, header bool, cookies []*http.Cookie) int {
+	form := url.Values{}
+	if !header {
+		form.Set("csrf_token", token)
+	}
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if header {
+		req.Header.Set("X-CSRF-Token", token)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w.Code
+}
+
+func testCSRF(t *testing.T, e *geeweb.Engine) {
+	token, cookies := getForm(t, e, nil)
+	if code := post(e, "/form", token, false, cookies); code != http.StatusOK {
+		t.Fatalf("valid form token should pass, got %d", code)
+	}
+	if code := post(e, "/form", token, true, cookies); code != http.StatusOK {
+		t.Fatalf("valid header token should pass, got %d", code)
+	}
+	// 每次渲染的 token 不同，但都可以通过校验
+	again, _ := getForm(t, e, cookies)
+	if again == token {
+		t.Fatal("token should be masked differently on every render")
+	}
+	if code := post(e, "/form", again, false, cookies); code != http.StatusOK {
+		t.Fatalf("re-rendered token should pass, got %d", code)
+	}
+
+	if code := post(e, "/form", "", false, cookies); code != http.StatusForbidden {
+		t.Fatalf("missing token should be rejected, got %d", code)
+	}
+	if code := post(e, "/form", token, false, nil); code != http.StatusForbidden {
+		t.Fatalf("token without cookie should be rejected, got %d", code)
+	}
+	other, _ := getForm(t, e, nil)
+	if code := post(e, "/form", other, false, cookies); code != http.StatusForbidden {
+		t.Fatalf("token of another client should be rejected, got %d", code)
+	}
+	if code := post(e, "/webhook/github", "", false, nil); code != http.StatusOK {
+		t.Fatalf("exempt path should pass, got %d", code)
+	}
+}
+
+func TestDoubleSubmit(t *testing.T) {
+	testCSRF(t, newEngine(t, Config{Secret: []byte("secret"), Exempt: []string{"/webhook/*"}}))
+
+	// 没有签名的 cookie 无效
+	e := newEngine(t, Config{Secret: []byte("secret")})
+	token, cookies := getForm(t, e, nil)
+	forged := *cookies[0]
+	forged.Value = strings.SplitN(forged.Value, ".", 2)[0] + ".forged"
+	if code := post(e, "/form", token, false, []*http.Cookie{&forged}); code != http.StatusForbidden {
+		t.Fatalf("forged cookie should be rejected, got %d", code)
+	}
+}
+
+func TestCookieSameSite(t *testing.T) {
+	e := geeweb.New()
+	e.Use(New(Config{Secret: []byte("secret")}))
+	e.GET("/", func(ctx *geeweb.Context) {
+		ctx.SetCookie("lang", "zh-CN", 0, "/", "", false, false)
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 2 || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("csrf cookie should be SameSite=Lax, got %v", w.Header()["Set-Cookie"])
+	}
+	// 之后 handler 设置的 Cookie 不受影响
+	if cookies[1].Name != "lang" || strings.Contains(w.Header()["Set-Cookie"][1], "SameSite") {
+		t.Fatalf("other cookies should keep the default SameSite, got %v", w.Header()["Set-Cookie"])
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	store, err := sessions.NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCSRF(t, newEngine(t, Config{UseSession: true, Exempt: []string{"/webhook/github"}},
+		sessions.Sessions("session", store, sessions.Options{})))
+}
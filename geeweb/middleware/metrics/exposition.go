@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType 是 Prometheus 文本格式 0.0.4 的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Expose 按 Prometheus 文本格式写出指标，同一指标的样本按标签排序，输出稳定
+func (m *Metrics) Expose(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	ns := m.namespace + "_http_"
+
+	writeHeader(bw, ns+"requests_total", "counter", "Total number of HTTP requests.")
+	for _, key := range sortedKeys(m.requests) {
+		writeSample(bw, ns+"requests_total", key.format(""), float64(m.requests[key]))
+	}
+
+	writeHeader(bw, ns+"requests_in_flight", "gauge", "Number of HTTP requests being served.")
+	gauges := make([]labels, 0, len(m.inFlight))
+	for key := range m.inFlight {
+		gauges = append(gauges, key)
+	}
+	sortLabels(gauges)
+	for _, key := range gauges {
+		writeSample(bw, ns+"requests_in_flight", key.format(""), float64(m.inFlight[key]))
+	}
+
+	writeHeader(bw, ns+"request_duration_seconds", "histogram", "HTTP request latency in seconds.")
+	writeHistograms(bw, ns+"request_duration_seconds", m.buckets, m.latency)
+	writeHeader(bw, ns+"response_size_bytes", "histogram", "HTTP response size in bytes.")
+	writeHistograms(bw, ns+"response_size_bytes", m.sizeBuckets, m.sizes)
+	return bw.Flush()
+}
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name + labels + " " + formatFloat(value) + "\n")
+}
+
+func writeHistograms(w *bufio.Writer, name string, buckets []float64, histograms map[labels]*histogram) {
+	keys := make([]labels, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sortLabels(keys)
+	for _, key := range keys {
+		histogram := histograms[key]
+		var cumulative uint64
+		for i, upper := range buckets {
+			cumulative += histogram.counts[i]
+			writeSample(w, name+"_bucket", key.format(`le="`+formatFloat(upper)+`"`), float64(cumulative))
+		}
+		writeSample(w, name+"_bucket", key.format(`le="+Inf"`), float64(histogram.count))
+		writeSample(w, name+"_sum", key.format(""), histogram.sum)
+		writeSample(w, name+"_count", key.format(""), float64(histogram.count))
+	}
+}
+
+// format 输出 {method="GET",route="/users/:id",status="2xx"}，extra 是附加的标签，比如 le
+func (l labels) format(extra string) string {
+	pairs := []string{`method="` + labelReplacer.Replace(l.method) + `"`, `route="` + labelReplacer.Replace(l.route) + `"`}
+	if l.status != "" {
+		pairs = append(pairs, `status="`+l.status+`"`)
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(m map[labels]uint64) []labels {
+	keys := make([]labels, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sortLabels(keys)
+	return keys
+}
+
+func sortLabels(keys []labels) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	geeweb "gee"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// unmatched 是没有匹配到路由的请求使用的 route 标签，避免把任意路径作为标签值
+const unmatched = "unmatched"
+
+var (
+	// DefaultBuckets 是延迟的分桶，单位为秒，和 Prometheus 客户端默认值一致
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DefaultSizeBuckets 是响应大小的分桶，单位为字节
+	DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+type Config struct {
+	// Namespace 是指标名的前缀，默认为 geeweb
+	Namespace string
+	Buckets   []float64
+	// SizeBuckets 是响应大小的分桶
+	SizeBuckets []float64
+	// SkipPaths 中的路由不统计，比如 /metrics 本身
+	SkipPaths []string
+}
+
+type labels struct {
+	method, route, status string
+}
+
+type histogram struct {
+	counts []uint64 // 每个桶内的数量，输出时再累加
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+	if i := sort.SearchFloat64s(buckets, v); i < len(buckets) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics 统计请求数、处理中的请求数、延迟和响应大小，标签为方法、路由 pattern 和状态码类别
+type Metrics struct {
+	namespace   string
+	buckets     []float64
+	sizeBuckets []float64
+	skip        map[string]bool
+
+	mu       sync.Mutex
+	requests map[labels]uint64
+	latency  map[labels]*histogram
+	sizes    map[labels]*histogram
+	inFlight map[labels]int64
+}
+
+func New(config Config) *Metrics {
+	m := &Metrics{
+		namespace:   config.Namespace,
+		buckets:     config.Buckets,
+		sizeBuckets: config.SizeBuckets,
+		skip:        make(map[string]bool),
+		requests:    make(map[labels]uint64),
+		latency:     make(map[labels]*histogram),
+		sizes:       make(map[labels]*histogram),
+		inFlight:    make(map[labels]int64),
+	}
+	if m.namespace == "" {
+		m.namespace = "geeweb"
+	}
+	if m.buckets == nil {
+		m.buckets = DefaultBuckets
+	}
+	if m.sizeBuckets == nil {
+		m.sizeBuckets = DefaultSizeBuckets
+	}
+	for _, path := range config.SkipPaths {
+		m.skip[path] = true
+	}
+	return m
+}
+
+// Middleware 需要通过 Engine.Use 注册，这样没有匹配到路由的请求也会被统计
+func (m *Metrics) Middleware() geeweb.HandlerFunc {
+	return func(ctx *geeweb.Context) {
+		route := ctx.FullPath()
+		if m.skip[route] {
+			return
+		}
+		if route == "" {
+			route = unmatched
+		}
+		method := methodLabel(ctx.Method)
+		gauge := labels{method: method, route: route}
+		m.mu.Lock()
+		m.inFlight[gauge]++
+		m.mu.Unlock()
+
+		start := time.Now()
+		defer func() {
+			// 放在 defer 中，panic 被外层的 Recovery 恢复时依然会减少处理中的请求数
+			elapsed := time.Since(start).Seconds()
+			size := ctx.Response.Size()
+			if size < 0 {
+				size = 0
+			}
+			key := labels{method: method, route: route, status: statusClass(ctx.Response.Status())}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.inFlight[gauge]--
+			m.requests[key]++
+			if m.latency[key] == nil {
+				m.latency[key] = &histogram{}
+				m.sizes[key] = &histogram{}
+			}
+			m.latency[key].observe(m.buckets, elapsed)
+			m.sizes[key].observe(m.sizeBuckets, float64(size))
+		}()
+		ctx.Next()
+	}
+}
+
+// knownMethods 之外的方法统一记为 OTHER，net/http 接受任意 token 作为方法，直接作为标签会无限增加时间序列
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
+
+func statusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// Handler 以 Prometheus 文本格式输出所有指标
+func (m *Metrics) Handler() geeweb.HandlerFunc {
+	return func(ctx *geeweb.Context) {
+		ctx.Response.Header().Set("Content-Type", ContentType)
+		ctx.Status(http.StatusOK)
+		_ = m.Expose(ctx.Response)
+	}
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	geeweb "gee"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New(Config{Buckets: []float64{0.1, 1}, SizeBuckets: []float64{10, 100}, SkipPaths: []string{"/metrics"}})
+	e := geeweb.New()
+	e.Use(m.Middleware())
+	e.GET("/users/:id", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "user %s", ctx.Param("id")) })
+	e.POST("/users", func(ctx *geeweb.Context) { ctx.AbortWithStatus(http.StatusBadRequest) })
+	e.GET("/metrics", m.Handler())
+
+	for _, r := range []struct{ method, path string }{
+		{"GET", "/users/1"}, {"GET", "/users/2"}, {"POST", "/users"}, {"GET", "/no/such/path"}, {"GET", "/metrics"},
+		{"FOO", "/no/such/path"}, {"BAR", "/no/such/path"},
+	} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE geeweb_http_requests_total counter",
+		`geeweb_http_requests_total{method="GET",route="/users/:id",status="2xx"} 2`,
+		`geeweb_http_requests_total{method="POST",route="/users",status="4xx"} 1`,
+		`geeweb_http_requests_total{method="GET",route="unmatched",status="4xx"} 1`,
+		`geeweb_http_requests_total{method="OTHER",route="unmatched",status="4xx"} 2`,
+		`geeweb_http_requests_in_flight{method="GET",route="/users/:id"} 0`,
+		"# TYPE geeweb_http_request_duration_seconds histogram",
+		`geeweb_http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="2xx",le="+Inf"} 2`,
+		`geeweb_http_request_duration_seconds_count{method="GET",route="/users/:id",status="2xx"} 2`,
+		`geeweb_http_response_size_bytes_bucket{method="GET",route="/users/:id",status="2xx",le="10"} 2`,
+		`geeweb_http_response_size_bytes_sum{method="GET",route="/users/:id",status="2xx"} 12`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("expect line %q in\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "/no/such/path") || strings.Contains(body, `route="/metrics"`) || strings.Contains(body, "FOO") {
+		t.Fatalf("raw paths, unknown methods and skipped routes should not be labels\n%s", body)
+	}
+}
+
+func TestLabelEscape(t *testing.T) {
+	l := labels{method: "GET", route: "/a\"b\\c\nd"}
+	if got := l.format(""); got != `{method="GET",route="/a\"b\\c\nd"}` {
+		t.Fatalf("unexpected labels %s", got)
+	}
+}
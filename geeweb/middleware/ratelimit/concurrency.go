@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"errors"
+	geeweb "gee"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded 表示正在处理和排队的请求都已经满了
+var ErrOverloaded = errors.New("server overloaded")
+
+type ConcurrencyConfig struct {
+	// MaxInFlight 是同时处理的最大请求数
+	MaxInFlight int
+	// MaxQueue 是等待处理的最大请求数，超过时直接返回 503
+	MaxQueue int
+	// QueueTimeout 是最长等待时间，默认一秒
+	QueueTimeout time.Duration
+	// RetryAfter 是 503 响应中建议的重试时间，默认一秒
+	RetryAfter time.Duration
+}
+
+// Concurrency 返回限制并发数的中间件，用于过载时丢弃请求，保护后端
+func Concurrency(config ConcurrencyConfig) geeweb.HandlerFunc {
+	if config.MaxInFlight <= 0 {
+		panic("ratelimit: MaxInFlight must be positive")
+	}
+	if config.QueueTimeout <= 0 {
+		config.QueueTimeout = time.Second
+	}
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	sem := make(chan struct{}, config.MaxInFlight)
+	var waiting int64
+	reject := func(ctx *geeweb.Context) {
+		ctx.Response.Header().Set("Retry-After", seconds(config.RetryAfter))
+		_ = ctx.AbortWithError(http.StatusServiceUnavailable, ErrOverloaded).SetType(geeweb.ErrorTypePublic)
+	}
+
+	return func(ctx *geeweb.Context) {
+		select {
+		case sem <- struct{}{}:
+		default:
+			if atomic.AddInt64(&waiting, 1) > int64(config.MaxQueue) {
+				atomic.AddInt64(&waiting, -1)
+				reject(ctx)
+				return
+			}
+			timer := time.NewTimer(config.QueueTimeout)
+			select {
+			case sem <- struct{}{}:
+				timer.Stop()
+				atomic.AddInt64(&waiting, -1)
+			case <-timer.C:
+				atomic.AddInt64(&waiting, -1)
+				reject(ctx)
+				return
+			case <-ctx.Done():
+				// 客户端已经断开，不需要再响应
+				timer.Stop()
+				atomic.AddInt64(&waiting, -1)
+				ctx.Abort()
+				return
+			}
+		}
+		defer func() { <-sem }()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"errors"
+	geeweb "gee"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrLimitExceeded 作为公开错误交给 Engine 的错误处理函数
+var ErrLimitExceeded = errors.New("rate limit exceeded")
+
+// KeyFunc 返回限流的 key，返回空字符串时不限流
+type KeyFunc func(ctx *geeweb.Context) string
+
+// ByIP 按 ClientIP 限流，代理后面需要先调用 Engine.SetTrustedProxies
+func ByIP(ctx *geeweb.Context) string {
+	return ctx.ClientIP()
+}
+
+// ByHeader 按请求头限流，比如 API Key，没有该头部的请求不限流
+func ByHeader(name string) KeyFunc {
+	return func(ctx *geeweb.Context) string {
+		return ctx.Request.Header.Get(name)
+	}
+}
+
+type Config struct {
+	Limit Limit
+	// KeyFunc 默认为 ByIP
+	KeyFunc KeyFunc
+	// Store 默认为每个中间件独立的 MemoryStore
+	Store Store
+	// Name 是 key 的前缀，多个中间件共用一个 Store 时用来区分各自的限额
+	Name string
+}
+
+// New 返回令牌桶限流中间件，Use 到分组上即为分组限额，放在路由的 handler 前面即为单个路由的限额
+func New(config Config) geeweb.HandlerFunc {
+	if config.Limit.Requests <= 0 || config.Limit.Period <= 0 {
+		panic("ratelimit: Limit.Requests and Limit.Period must be positive")
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = ByIP
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	prefix := ""
+	if config.Name != "" {
+		prefix = config.Name + ":"
+	}
+	return func(ctx *geeweb.Context) {
+		key := config.KeyFunc(ctx)
+		if key == "" {
+			return
+		}
+		result, err := config.Store.Take(prefix+key, config.Limit)
+		if err != nil {
+			// 存储不可用时放行，限流不应该影响正常请求
+			log.Printf("[geeweb] ratelimit: %v", err)
+			return
+		}
+		header := ctx.Response.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", seconds(result.Reset))
+		if !result.Allowed {
+			header.Set("Retry-After", seconds(result.RetryAfter))
+			_ = ctx.AbortWithError(http.StatusTooManyRequests, ErrLimitExceeded).SetType(geeweb.ErrorTypePublic)
+		}
+	}
+}
+
+// seconds 向上取整，Retry-After 为 0 会让客户端立即重试
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	geeweb "gee"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Period: time.Second}
+
+	for i := 0; i < 2; i++ {
+		if r, _ := s.Take("a", limit); !r.Allowed || r.Remaining != 1-i {
+			t.Fatalf("request %d should be allowed, got %+v", i, r)
+		}
+	}
+	r, _ := s.Take("a", limit)
+	if r.Allowed || r.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("third request should be limited, got %+v", r)
+	}
+	if r, _ := s.Take("b", limit); !r.Allowed {
+		t.Fatal("keys should have separate buckets")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if r, _ := s.Take("a", limit); !r.Allowed {
+		t.Fatal("token should be refilled")
+	}
+	now = now.Add(time.Hour)
+	s.Take("c", limit)
+	if len(s.buckets) != 1 {
+		t.Fatalf("idle buckets should be swept, got %d", len(s.buckets))
+	}
+}
+
+func TestMemoryStoreSharedLimits(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	strict := Limit{Requests: 1, Period: time.Hour}
+	loose := PerSecond(100)
+
+	if r, _ := s.Take("strict:1.2.3.4", strict); !r.Allowed {
+		t.Fatal("first strict request should be allowed")
+	}
+	now = now.Add(2 * sweepInterval)
+	// 按 loose 的限额清理时不能删除 strict 还没有装满的桶
+	s.Take("loose:1.2.3.4", loose)
+	if r, _ := s.Take("strict:1.2.3.4", strict); r.Allowed {
+		t.Fatal("strict limit should survive a sweep triggered by a looser limit")
+	}
+}
+
+func TestMemoryStoreSubNanosecondInterval(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	// 每个令牌需要 2/3 纳秒，不能按 0 计算
+	limit := Limit{Requests: 3, Period: 2 * time.Nanosecond}
+
+	for i := 0; i < 3; i++ {
+		s.Take("a", limit)
+	}
+	if r, _ := s.Take("a", limit); r.Allowed {
+		t.Fatalf("fourth request should be limited, got %+v", r)
+	}
+	now = now.Add(time.Nanosecond)
+	if r, _ := s.Take("a", limit); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("1ns should refill 1.5 tokens, got %+v", r)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	e := geeweb.New()
+	api := e.Group("/api")
+	api.Use(New(Config{Limit: PerMinute(2)}))
+	api.GET("/users", func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "users") })
+	api.GET("/search", New(Config{Limit: PerMinute(1), KeyFunc: ByHeader("X-API-Key")}),
+		func(ctx *geeweb.Context) { ctx.String(http.StatusOK, "search") })
+
+	do := func(path, ip, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+	do("/api/users", "10.0.0.1", "")
+	w := do("/api/users", "10.0.0.1", "")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	w = do("/api/users", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expect 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+	if w = do("/api/users", "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Fatal("other clients should not be limited")
+	}
+
+	// 路由的限额按 API Key 计算，和分组的限额叠加
+	if w = do("/api/search", "10.0.0.3", "k1"); w.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d", w.Code)
+	}
+	if w = do("/api/search", "10.0.0.4", "k1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expect 429, got %d", w.Code)
+	}
+	if w = do("/api/search", "10.0.0.4", "k2"); w.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d", w.Code)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	e := geeweb.New()
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	e.Use(Concurrency(ConcurrencyConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: time.Second}))
+	e.GET("/", func(ctx *geeweb.Context) {
+		started <- struct{}{}
+		<-release
+	})
+
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			codes[i] = w.Code
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+	// 等待第二个请求进入队列
+	time.Sleep(50 * time.Millisecond)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("request beyond the queue should be shed, got %d", w.Code)
+	}
+	close(release)
+	wg.Wait()
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK {
+		t.Fatalf("queued request should be served, got %v", codes)
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit 表示每 Period 时间内允许 Requests 个请求，Burst 是桶的容量，默认等于 Requests
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// PerSecond 和 PerMinute 是构造 Limit 的快捷方式
+func PerSecond(n int) Limit { return Limit{Requests: n, Period: time.Second} }
+func PerMinute(n int) Limit { return Limit{Requests: n, Period: time.Minute} }
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// interval 是生成一个令牌需要的纳秒数，Requests 大于 Period 的纳秒数时整数除法会得到 0，所以用浮点数
+func (l Limit) interval() float64 {
+	return float64(l.Period) / float64(l.Requests)
+}
+
+// Result 是一次 Take 的结果，用于设置 X-RateLimit-* 和 Retry-After 头部
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset 是令牌桶重新装满需要的时间
+	Reset time.Duration
+	// RetryAfter 是被拒绝时下一个令牌生成需要的时间
+	RetryAfter time.Duration
+}
+
+// Store 保存每个 key 的令牌桶，默认的 MemoryStore 只在单机内生效，
+// 多个实例共享限额时可以基于 geecache 或其他存储实现这个接口
+type Store interface {
+	Take(key string, limit Limit) (Result, error)
+}
+
+// bucket 记录自己的限额，共享同一个 Store 的多个 Limit 在清理时互不影响
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	burst    float64
+	interval float64
+}
+
+// MemoryStore 是基于内存的令牌桶，长时间没有访问的桶会被定期清理
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (Result, error) {
+	now := s.now()
+	burst := float64(limit.burst())
+	interval := limit.interval()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	b.burst, b.interval = burst, interval
+	// 按经过的时间补充令牌，不超过桶的容量
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+float64(elapsed)/interval)
+		b.last = now
+	}
+
+	result := Result{Limit: limit.burst()}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * interval)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((burst - b.tokens) * interval)
+	return result, nil
+}
+
+// sweep 删除已经装满的桶，它们和新建的桶没有区别
+func (s *MemoryStore) sweep(now time.Time) {
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if b.tokens+float64(now.Sub(b.last))/b.interval >= b.burst {
+			delete(s.buckets, key)
+		}
+	}
+}
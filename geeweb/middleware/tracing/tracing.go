@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"fmt"
+	geeweb "gee"
+	"geetrace"
+	"net/http"
+	"strconv"
+)
+
+// unmatched 是没有匹配到路由的请求使用的 span 名，避免把任意路径作为 span 名
+const unmatched = "unmatched"
+
+type Config struct {
+	// Tracer 为空时使用 geetrace.Default()
+	Tracer *geetrace.Tracer
+	// SkipPaths 中的路由不创建 span，比如健康检查
+	SkipPaths []string
+}
+
+// Default 使用全局的 Tracer
+func Default() geeweb.HandlerFunc {
+	return New(Config{})
+}
+
+// New 返回的中间件需要通过 Engine.Use 注册，它从请求头中读取 traceparent 和 tracestate，
+// 开始一个 server span 并放入 ctx.Request 的 context，handler 把 ctx 传给 geerpc 或 geecache 时链路会继续传递
+func New(config Config) geeweb.HandlerFunc {
+	skip := make(map[string]bool)
+	for _, path := range config.SkipPaths {
+		skip[path] = true
+	}
+	return func(ctx *geeweb.Context) {
+		route := ctx.FullPath()
+		if skip[route] {
+			return
+		}
+		if route == "" {
+			route = unmatched
+		}
+		tracer := config.Tracer
+		if tracer == nil {
+			tracer = geetrace.Default()
+		}
+		parent := geetrace.Extract(ctx.Request.Context(), geetrace.HeaderCarrier(ctx.Request.Header))
+		spanCtx, span := tracer.Start(parent, ctx.Method+" "+route, geetrace.SpanKindServer)
+		span.SetAttribute("http.method", ctx.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", ctx.Request.URL.RequestURI())
+		span.SetAttribute("http.client_ip", ctx.ClientIP())
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+
+		defer func() {
+			// panic 被外层的 Recovery 恢复时 span 也会结束，并记录为错误
+			if err := recover(); err != nil {
+				span.SetError(fmt.Errorf("panic: %v", err))
+				span.End()
+				panic(err)
+			}
+			status := ctx.Response.Status()
+			span.SetAttribute("http.status_code", strconv.Itoa(status))
+			if last := ctx.Errors.Last(); last != nil {
+				span.SetError(last)
+			} else if status >= http.StatusInternalServerError {
+				span.SetError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+			}
+			span.End()
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	geeweb "gee"
+	"geetrace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := geetrace.NewInMemoryExporter()
+	e := geeweb.New()
+	e.Use(New(Config{Tracer: geetrace.NewTracer(exporter), SkipPaths: []string{"/healthz"}}))
+	var inner geetrace.SpanContext
+	e.GET("/users/:id", func(ctx *geeweb.Context) {
+		// Context 实现了 context.Context，可以直接传给下游
+		var c context.Context = ctx
+		inner = geetrace.SpanContextFromContext(c)
+		ctx.String(http.StatusOK, "ok")
+	})
+	e.GET("/fail", func(ctx *geeweb.Context) {
+		ctx.AbortWithError(http.StatusInternalServerError, errors.New("db down"))
+	})
+	e.GET("/healthz", func(ctx *geeweb.Context) { ctx.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expect 3 spans, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /users/:id" || span.Kind != "server" || span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		span.ParentID != "00f067aa0ba902b7" || span.Attributes["http.status_code"] != "200" {
+		t.Fatalf("unexpected span %+v", span)
+	}
+	if inner.SpanID.String() != span.SpanID {
+		t.Fatal("handler should see the server span in its context")
+	}
+	if spans[1].Name != "GET /fail" || spans[1].Error != "db down" {
+		t.Fatalf("unexpected error span %+v", spans[1])
+	}
+	if spans[2].Name != "GET unmatched" || spans[2].Attributes["http.status_code"] != "404" {
+		t.Fatalf("unexpected unmatched span %+v", spans[2])
+	}
+}
@@ -1,35 +1,75 @@
 package geeweb
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"net"
 	"net/http"
+	"os"
 	"runtime"
 	"strings"
-	"time"
 )
 
-func Logger() HandlerFunc {
-	return func(ctx *Context) {
-		t := time.Now()
-		ctx.Next()
-		log.Printf("[%d] %s in %v", ctx.StatusCode, ctx.Request.RequestURI, time.Since(t))
-	}
-}
+// RecoveryFunc 在 panic 被恢复之后调用，err 是 recover 的返回值
+type RecoveryFunc func(ctx *Context, err interface{})
 
 func Recovery() HandlerFunc {
+	return RecoveryWithWriter(os.Stderr)
+}
+
+func CustomRecovery(handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithWriter(os.Stderr, handle)
+}
+
+// RecoveryWithWriter 把调用栈写入 out，handle 为空时返回 500，具体内容由 ErrorHandler 决定
+func RecoveryWithWriter(out io.Writer, handle ...RecoveryFunc) HandlerFunc {
+	recovery := defaultRecovery
+	if len(handle) > 0 {
+		recovery = handle[0]
+	}
 	return func(ctx *Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				// 客户端断开连接导致的写入失败不是程序错误，也无法再写出响应
+				if isBrokenPipe(err) {
+					fmt.Fprintf(out, "[geeweb] %s %s: connection closed by client: %v\n", ctx.Method, ctx.Path, err)
+					if e, ok := err.(error); ok {
+						ctx.Error(e)
+					}
+					ctx.Abort()
+					return
+				}
 				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
+				fmt.Fprintf(out, "%s\n\n", trace(message))
+				recovery(ctx, err)
 			}
 		}()
 		ctx.Next()
 	}
 }
 
+func defaultRecovery(ctx *Context, err interface{}) {
+	ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("panic: %v", err))
+}
+
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
 func trace(message string) string {
 	var pcs [32]uintptr // 最多显示 32 层调用？
 	var str strings.Builder
@@ -0,0 +1,58 @@
+package geeweb
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+)
+
+const (
+	// DebugMode 下启动时打印路由表，是默认模式
+	DebugMode   = "debug"
+	ReleaseMode = "release"
+)
+
+// EnvMode 环境变量可以在不修改代码的情况下设置模式
+const EnvMode = "GEEWEB_MODE"
+
+var debugMode int32 = 1
+
+func init() {
+	modeFromEnv(os.Getenv(EnvMode))
+}
+
+// modeFromEnv 在环境变量的值不认识时打印警告并使用 DebugMode，
+// 不能像 SetMode 一样 panic，否则引用这个包的程序和测试都无法启动
+func modeFromEnv(mode string) {
+	switch mode {
+	case "":
+	case DebugMode, ReleaseMode:
+		SetMode(mode)
+	default:
+		log.Printf("[geeweb] unknown %s %q, using %s mode", EnvMode, mode, DebugMode)
+		SetMode(DebugMode)
+	}
+}
+
+// SetMode 设置全局的运行模式，不认识的模式会 panic
+func SetMode(mode string) {
+	switch mode {
+	case DebugMode:
+		atomic.StoreInt32(&debugMode, 1)
+	case ReleaseMode:
+		atomic.StoreInt32(&debugMode, 0)
+	default:
+		panic("geeweb: unknown mode " + mode)
+	}
+}
+
+func Mode() string {
+	if IsDebugging() {
+		return DebugMode
+	}
+	return ReleaseMode
+}
+
+func IsDebugging() bool {
+	return atomic.LoadInt32(&debugMode) == 1
+}
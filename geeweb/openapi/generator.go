@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	geeweb "gee"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Route 是路由的附加说明，都是可选的
+type Route struct {
+	Summary     string
+	Description string
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+	// Params 是使用 uri、form 和 header 标签的结构体，对应路径、查询和请求头参数
+	Params interface{}
+	// Body 是请求体的类型，按 JSON 生成 schema
+	Body interface{}
+	// Responses 是状态码到响应类型的映射，值为 nil 表示没有响应体，默认只有 200
+	Responses map[int]interface{}
+}
+
+// Generator 根据 Engine 注册的路由和 Describe 添加的说明生成文档，每次请求时重新生成，所以可以在注册路由之前创建
+type Generator struct {
+	engine  *geeweb.Engine
+	info    Info
+	servers []Server
+
+	mu     sync.RWMutex
+	routes map[string]Route
+}
+
+func New(engine *geeweb.Engine, info Info, servers ...Server) *Generator {
+	return &Generator{engine: engine, info: info, servers: servers, routes: make(map[string]Route)}
+}
+
+// Describe 为 method 和完整路由 pattern 添加说明，pattern 与注册时相同，包括分组前缀
+func (g *Generator) Describe(method, pattern string, route Route) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.routes[routeKey(method, pattern)] = route
+}
+
+func routeKey(method, pattern string) string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(pattern, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.ToUpper(method) + " /" + strings.Join(parts, "/")
+}
+
+// operationMethods 是 OpenAPI 3.0 中 PathItem 支持的方法，CONNECT 等其他方法不会出现在文档中
+var operationMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+}
+
+// Document 生成当前的文档
+func (g *Generator) Document() *Document {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    g.info,
+		Servers: g.servers,
+		Paths:   make(map[string]PathItem),
+	}
+	for _, info := range g.engine.Routes() {
+		// 文档只描述默认路由树，Host 注册的路由需要单独的文档
+		if info.Host != "" || !operationMethods[info.Method] {
+			continue
+		}
+		path, pathParams := convertPath(info.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(info.Method)] = g.operation(s, info, pathParams)
+	}
+	if len(s.components) > 0 {
+		doc.Components.Schemas = s.components
+	}
+	return doc
+}
+
+func (g *Generator) operation(s *schemas, info geeweb.RouteInfo, pathParams []string) *Operation {
+	route := g.routes[routeKey(info.Method, info.Path)]
+	op := &Operation{
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: route.OperationID,
+		Tags:        route.Tags,
+		Deprecated:  route.Deprecated,
+		Responses:   make(map[string]*Response),
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(info.Method, info.Path)
+	}
+
+	// 路由中的参数总是存在，Params 中的 uri 字段可以补充类型和说明
+	declared := make(map[string]bool)
+	if route.Params != nil {
+		for _, p := range s.parameters(reflect.TypeOf(route.Params)) {
+			if p.In == "path" {
+				p.Required = true
+			}
+			declared[p.In+":"+p.Name] = true
+			op.Parameters = append(op.Parameters, p)
+		}
+	}
+	for _, name := range pathParams {
+		if !declared["path:"+name] {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{geeweb.MIMEJSON: {Schema: s.schemaOf(reflect.TypeOf(route.Body))}},
+		}
+	}
+	if len(route.Responses) == 0 {
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+	for code, v := range route.Responses {
+		resp := &Response{Description: http.StatusText(code)}
+		if v != nil {
+			resp.Content = map[string]MediaType{geeweb.MIMEJSON: {Schema: s.schemaOf(reflect.TypeOf(v))}}
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+	return op
+}
+
+// parameters 读取结构体中 uri、form 和 header 标签的字段
+func (s *schemas) parameters(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			params = append(params, s.parameters(field.Type)...)
+			continue
+		}
+		for _, loc := range [...]struct{ tag, in string }{{"uri", "path"}, {"form", "query"}, {"header", "header"}} {
+			name := strings.Split(field.Tag.Get(loc.tag), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			schema := s.schemaOf(field.Type)
+			required := applyRules(schema, field.Tag.Get("binding"))
+			params = append(params, Parameter{
+				Name:        name,
+				In:          loc.in,
+				Description: field.Tag.Get("description"),
+				Required:    required,
+				Schema:      schema,
+			})
+		}
+	}
+	return params
+}
+
+// convertPath 把 :name 和 *name 转换成 {name}，返回路径参数的名字
+func convertPath(pattern string) (string, []string) {
+	parts := strings.Split(pattern, "/")
+	var params []string
+	for i, part := range parts {
+		if len(part) > 1 && (part[0] == ':' || part[0] == '*') {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// operationID 默认为方法和路径拼接，比如 get_users_id
+func operationID(method, pattern string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.Split(pattern, "/") {
+		part = strings.TrimLeft(part, ":*")
+		if part != "" {
+			id += "_" + part
+		}
+	}
+	return id
+}
+
+// Handler 返回文档，路径以 .yaml 或 .yml 结尾或 Accept 偏好 YAML 时返回 YAML，否则返回 JSON
+func (g *Generator) Handler() geeweb.HandlerFunc {
+	return func(ctx *geeweb.Context) {
+		doc := g.Document()
+		if strings.HasSuffix(ctx.Path, ".yaml") || strings.HasSuffix(ctx.Path, ".yml") ||
+			ctx.NegotiateFormat(geeweb.MIMEJSON, geeweb.MIMEYAML) == geeweb.MIMEYAML {
+			ctx.YAML(http.StatusOK, doc)
+			return
+		}
+		ctx.JSON(http.StatusOK, doc)
+	}
+}
+
+// Serve 在分组下注册 GET path 返回文档，比如 g.Serve(engine.RouterGroup, "/openapi.json")
+func (g *Generator) Serve(group *geeweb.RouterGroup, path string) {
+	group.GET(path, g.Handler())
+}
@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"encoding/json"
+	geeweb "gee"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" binding:"required,min=2,max=20" description:"user name"`
+	Email     string    `json:"email,omitempty" binding:"email"`
+	Role      string    `json:"role" binding:"oneof=admin user"`
+	CreatedAt time.Time `json:"created_at"`
+	Friends   []*User   `json:"friends,omitempty"`
+	password  string
+}
+
+type UserParams struct {
+	ID      int    `uri:"id" description:"user id"`
+	Verbose bool   `form:"verbose"`
+	Token   string `header:"X-Token" binding:"required"`
+}
+
+func newGenerator() (*geeweb.Engine, *Generator) {
+	e := geeweb.New()
+	g := New(e, Info{Title: "geeweb", Version: "1.0"})
+	v1 := e.Group("/v1")
+	v1.GET("/users/:id", func(ctx *geeweb.Context) {})
+	v1.POST("/users", func(ctx *geeweb.Context) {})
+	e.GET("/assets/*filepath", func(ctx *geeweb.Context) {})
+	g.Describe("GET", "/v1/users/:id", Route{
+		Summary:   "get user",
+		Tags:      []string{"users"},
+		Params:    UserParams{},
+		Responses: map[int]interface{}{200: User{}, 404: nil},
+	})
+	g.Describe("POST", "/v1/users/", Route{Body: &User{}, Responses: map[int]interface{}{201: User{}}})
+	g.Serve(e.RouterGroup, "/openapi.json")
+	g.Serve(e.RouterGroup, "/openapi.yaml")
+	return e, g
+}
+
+func TestDocument(t *testing.T) {
+	_, g := newGenerator()
+	doc := g.Document()
+
+	get := doc.Paths["/v1/users/{id}"]["get"]
+	if get == nil || get.Summary != "get user" || get.OperationID != "get_v1_users_id" {
+		t.Fatalf("unexpected operation %+v", get)
+	}
+	if len(get.Parameters) != 3 {
+		t.Fatalf("expect 3 parameters, got %+v", get.Parameters)
+	}
+	id, verbose, token := get.Parameters[0], get.Parameters[1], get.Parameters[2]
+	if id.In != "path" || !id.Required || id.Schema.Type != "integer" || id.Description != "user id" ||
+		verbose.In != "query" || verbose.Required || verbose.Schema.Type != "boolean" ||
+		token.Name != "X-Token" || token.In != "header" || !token.Required {
+		t.Fatalf("unexpected parameters %+v %+v %+v", id, verbose, token)
+	}
+	if get.Responses["404"].Content != nil || get.Responses["200"].Content[geeweb.MIMEJSON].Schema.Ref != "#/components/schemas/User" {
+		t.Fatalf("unexpected responses %+v", get.Responses)
+	}
+
+	user := doc.Components.Schemas["User"]
+	if user == nil || len(user.Required) != 1 || user.Required[0] != "name" {
+		t.Fatalf("unexpected user schema %+v", user)
+	}
+	name := user.Properties["name"]
+	if *name.MinLength != 2 || *name.MaxLength != 20 || name.Description != "user name" {
+		t.Fatalf("unexpected name schema %+v", name)
+	}
+	if user.Properties["email"].Format != "email" || len(user.Properties["role"].Enum) != 2 ||
+		user.Properties["created_at"].Format != "date-time" || user.Properties["friends"].Items.Ref != "#/components/schemas/User" {
+		t.Fatalf("unexpected properties %+v", user.Properties)
+	}
+	if _, ok := user.Properties["password"]; ok {
+		t.Fatal("unexported fields should be skipped")
+	}
+
+	if body := doc.Paths["/v1/users"]["post"].RequestBody; body == nil || body.Content[geeweb.MIMEJSON].Schema.Ref == "" {
+		t.Fatalf("unexpected request body %+v", body)
+	}
+	assets := doc.Paths["/assets/{filepath}"]["get"]
+	if len(assets.Parameters) != 1 || assets.Parameters[0].Name != "filepath" || assets.Responses["200"] == nil {
+		t.Fatalf("unexpected wildcard operation %+v", assets)
+	}
+}
+
+func TestServe(t *testing.T) {
+	e, _ := newGenerator()
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || doc["openapi"] != "3.0.3" {
+		t.Fatalf("expect JSON document, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	body := w.Body.String()
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), geeweb.MIMEYAML) || !strings.Contains(body, `openapi: "3.0.3"`) ||
+		!strings.Contains(body, `"$ref": "#/components/schemas/User"`) {
+		t.Fatalf("expect YAML document, got %q", body)
+	}
+}
+
+func TestAnyRoute(t *testing.T) {
+	e := geeweb.New()
+	g := New(e, Info{Title: "geeweb", Version: "1.0"})
+	e.Any("/echo", func(ctx *geeweb.Context) {})
+	item := g.Document().Paths["/echo"]
+	if len(item) != 8 || item["get"] == nil || item["trace"] == nil {
+		t.Fatalf("expect the 8 OpenAPI methods, got %v", item)
+	}
+	if _, ok := item["connect"]; ok {
+		t.Fatal("connect is not a valid OpenAPI operation")
+	}
+}
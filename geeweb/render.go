@@ -0,0 +1,203 @@
+package geeweb
+
+import (
+	"fmt"
+	"gee/render"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJSON     = "application/json"
+	MIMEHTML     = "text/html"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/x-yaml"
+	MIMEProtoBuf = "application/x-protobuf"
+)
+
+// Render 写出响应，渲染失败时返回 500 并终止处理链
+func (c *Context) Render(code int, r render.Render) {
+	if err := r.Render(c.Response, code); err != nil {
+		c.renderError(err)
+	}
+}
+
+// renderError 记录错误，还没有写出任何内容时由 ErrorHandler 返回 500
+func (c *Context) renderError(err error) {
+	log.Printf("[geeweb] render %s failed: %v", c.Path, err)
+	if c.Response.Written() {
+		c.Abort()
+		c.Error(err).SetType(ErrorTypeRender)
+		return
+	}
+	c.AbortWithError(http.StatusInternalServerError, err).SetType(ErrorTypeRender)
+}
+
+func (c *Context) IndentedJSON(code int, v interface{}) {
+	c.Render(code, render.IndentedJSON{Data: v})
+}
+
+// SecureJSON 为数组加上 while(1); 前缀
+func (c *Context) SecureJSON(code int, v interface{}) {
+	c.Render(code, render.SecureJSON{Prefix: "while(1);", Data: v})
+}
+
+// JSONP 从 query 参数 callback 中读取回调函数名
+func (c *Context) JSONP(code int, v interface{}) {
+	c.Render(code, render.JSONP{Callback: c.Query("callback"), Data: v})
+}
+
+func (c *Context) XML(code int, v interface{}) {
+	c.Render(code, render.XML{Data: v})
+}
+
+func (c *Context) YAML(code int, v interface{}) {
+	c.Render(code, render.YAML{Data: v})
+}
+
+func (c *Context) ProtoBuf(code int, v proto.Message) {
+	c.Render(code, render.ProtoBuf{Data: v})
+}
+
+func (c *Context) Redirect(code int, location string) {
+	c.Render(code, render.Redirect{Request: c.Request, Location: location})
+}
+
+// DataFromReader 直接把 reader 的内容写入响应，contentLength 小于 0 表示未知长度
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	c.Render(code, render.Reader{
+		ContentType:   contentType,
+		ContentLength: contentLength,
+		Headers:       extraHeaders,
+		Reader:        reader,
+	})
+}
+
+// File 由 http.ServeFile 处理 Range、If-Modified-Since 等请求头
+func (c *Context) File(filePath string) {
+	http.ServeFile(c.Response, c.Request, filePath)
+}
+
+// FileAttachment 让浏览器以 filename 下载文件
+func (c *Context) FileAttachment(filePath, filename string) {
+	if filename == "" {
+		filename = filepath.Base(filePath)
+	}
+	disposition := fmt.Sprintf("attachment; filename=%q", filename)
+	if !isASCII(filename) {
+		disposition = "attachment; filename*=UTF-8''" + url.PathEscape(filename)
+	}
+	c.SetHeader("Content-Disposition", disposition)
+	http.ServeFile(c.Response, c.Request, filePath)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// Negotiate 根据 Accept 头在 Offered 中选择返回的格式
+type Negotiate struct {
+	Offered  []string
+	HTMLName string
+	Data     interface{}
+}
+
+// Negotiate 没有可接受的格式时返回 406
+func (c *Context) Negotiate(code int, config Negotiate) {
+	switch c.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, config.Data)
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, config.Data)
+	case MIMEXML, MIMEXML2:
+		c.XML(code, config.Data)
+	case MIMEYAML:
+		c.YAML(code, config.Data)
+	case MIMEProtoBuf:
+		if msg, ok := config.Data.(proto.Message); ok {
+			c.ProtoBuf(code, msg)
+			return
+		}
+		c.renderError(fmt.Errorf("%T is not a proto.Message", config.Data))
+	case MIMEPlain:
+		c.String(code, "%v", config.Data)
+	default:
+		c.Abort()
+		c.String(http.StatusNotAcceptable, "the accepted formats are not offered by the server")
+	}
+}
+
+type acceptItem struct {
+	mime string
+	q    float64
+}
+
+// parseAccept 解析 Accept 头并按 q 值从高到低排序
+func parseAccept(header string) []acceptItem {
+	items := make([]acceptItem, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mime := strings.TrimSpace(fields[0])
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, acceptItem{mime: mime, q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	return items
+}
+
+// NegotiateFormat 返回 offered 中客户端最想要的格式，没有 Accept 头时返回 offered[0]
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("geeweb: you must provide at least one offer")
+	}
+	accept := c.Request.Header.Get("Accept")
+	if accept == "" {
+		return offered[0]
+	}
+	for _, item := range parseAccept(accept) {
+		for _, offer := range offered {
+			if matchMIME(item.mime, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// matchMIME 支持 */* 和 text/* 这样的通配符
+func matchMIME(accepted, offer string) bool {
+	if accepted == "*/*" || accepted == offer {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(offer, accepted[:len(accepted)-1])
+	}
+	return false
+}
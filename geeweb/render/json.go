@@ -0,0 +1,70 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"reflect"
+)
+
+type JSON struct {
+	Data interface{}
+}
+
+func (r JSON) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "application/json; charset=utf-8", func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(r.Data)
+	})
+}
+
+type IndentedJSON struct {
+	Data interface{}
+}
+
+func (r IndentedJSON) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "application/json; charset=utf-8", func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(r.Data)
+	})
+}
+
+// SecureJSON 在数组前加上 Prefix，防止 JSON 劫持
+type SecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+func (r SecureJSON) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "application/json; charset=utf-8", func(w io.Writer) error {
+		v := reflect.ValueOf(r.Data)
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			if _, err := io.WriteString(w, r.Prefix); err != nil {
+				return err
+			}
+		}
+		return json.NewEncoder(w).Encode(r.Data)
+	})
+}
+
+// JSONP 在 Callback 不为空时返回 callback(data); 否则与 JSON 相同
+type JSONP struct {
+	Callback string
+	Data     interface{}
+}
+
+func (r JSONP) Render(w http.ResponseWriter, code int) error {
+	if r.Callback == "" {
+		return JSON{Data: r.Data}.Render(w, code)
+	}
+	return writeBuffered(w, code, "application/javascript; charset=utf-8", func(w io.Writer) error {
+		data, err := json.Marshal(r.Data)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s(%s);", template.JSEscapeString(r.Callback), data)
+		return err
+	})
+}
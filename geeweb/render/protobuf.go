@@ -0,0 +1,22 @@
+package render
+
+import (
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+type ProtoBuf struct {
+	Data proto.Message
+}
+
+func (r ProtoBuf) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "application/x-protobuf", func(w io.Writer) error {
+		data, err := proto.Marshal(r.Data)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
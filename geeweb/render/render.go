@@ -0,0 +1,113 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Render 负责写出响应，包括 Content-Type、状态码和响应体
+// 需要编码的 Render 先编码到缓冲区，编码失败时不会向 w 写入任何内容，调用者可以返回一个干净的 500
+type Render interface {
+	Render(w http.ResponseWriter, code int) error
+}
+
+func writeContentType(w http.ResponseWriter, contentType string) {
+	header := w.Header()
+	if contentType != "" && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", contentType)
+	}
+}
+
+// writeBuffered 先调用 encode 写入缓冲区，成功后再写状态码和响应体
+func writeBuffered(w http.ResponseWriter, code int, contentType string, encode func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := encode(&buf); err != nil {
+		return err
+	}
+	writeContentType(w, contentType)
+	w.WriteHeader(code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type String struct {
+	Format string
+	Data   []interface{}
+}
+
+func (r String) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "text/plain; charset=utf-8", func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, r.Format, r.Data...)
+		return err
+	})
+}
+
+type Data struct {
+	ContentType string
+	Data        []byte
+}
+
+func (r Data) Render(w http.ResponseWriter, code int) error {
+	writeContentType(w, r.ContentType)
+	w.WriteHeader(code)
+	_, err := w.Write(r.Data)
+	return err
+}
+
+type HTML struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+func (r HTML) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "text/html; charset=utf-8", func(w io.Writer) error {
+		if r.Template == nil {
+			return fmt.Errorf("render: html template %q not loaded", r.Name)
+		}
+		if r.Name == "" {
+			return r.Template.Execute(w, r.Data)
+		}
+		return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+	})
+}
+
+// Redirect 的 code 必须是 3xx，POST 之后返回 201 也可以带 Location
+type Redirect struct {
+	Request  *http.Request
+	Location string
+}
+
+func (r Redirect) Render(w http.ResponseWriter, code int) error {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect) && code != http.StatusCreated {
+		return fmt.Errorf("render: cannot redirect with status code %d", code)
+	}
+	http.Redirect(w, r.Request, r.Location, code)
+	return nil
+}
+
+// Reader 把 Reader 中的内容直接写入响应，不经过缓冲
+type Reader struct {
+	ContentType   string
+	ContentLength int64 // 小于 0 表示未知长度
+	Headers       map[string]string
+	Reader        io.Reader
+}
+
+func (r Reader) Render(w http.ResponseWriter, code int) error {
+	header := w.Header()
+	for k, v := range r.Headers {
+		header.Set(k, v)
+	}
+	if r.ContentLength >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	writeContentType(w, r.ContentType)
+	w.WriteHeader(code)
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type yamlUser struct {
+	Name    string            `yaml:"name"`
+	Age     int               `yaml:"age,omitempty"`
+	Tags    []string          `yaml:"tags"`
+	Labels  map[string]string `yaml:"labels"`
+	Created time.Time
+	Secret  string `yaml:"-"`
+}
+
+func TestMarshalYAML(t *testing.T) {
+	u := yamlUser{
+		Name:    "geektutu",
+		Tags:    []string{"go", "yes"},
+		Labels:  map[string]string{"b": "2", "a": "x: y"},
+		Created: time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+		Secret:  "secret",
+	}
+	data, err := MarshalYAML([]interface{}{u, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `-
+  name: geektutu
+  tags:
+    - go
+    - "yes"
+  labels:
+    a: "x: y"
+    b: "2"
+  created: "2022-05-01T00:00:00Z"
+- null
+`
+	if string(data) != expect {
+		t.Fatalf("expect:\n%s\ngot:\n%s", expect, data)
+	}
+}
+
+func TestJSONP(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (JSONP{Callback: "cb", Data: map[string]int{"a": 1}}).Render(w, http.StatusOK); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != `cb({"a":1});` || w.Header().Get("Content-Type") != "application/javascript; charset=utf-8" {
+		t.Fatalf("unexpected jsonp response %q %q", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
+
+func TestBufferedRenderError(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := (JSON{Data: make(chan int)}).Render(w, http.StatusOK); err == nil {
+		t.Fatal("encoding a channel should fail")
+	}
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Fatal("nothing should be written when encoding fails")
+	}
+}
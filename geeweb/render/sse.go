@@ -0,0 +1,60 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SSEvent 是 Server-Sent Events 中的一个事件，Data 不是字符串时按 JSON 编码
+type SSEvent struct {
+	Event string
+	ID    string
+	Retry uint // 毫秒，0 表示不设置
+	Data  interface{}
+}
+
+var sseReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+// sseLineReplacer 统一换行符，\r\n 和单独的 \r 在 EventSource 中都是行结束符
+var sseLineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// Encode 按 text/event-stream 格式写出事件，多行数据会拆成多个 data 字段
+func (e SSEvent) Encode(w io.Writer) error {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + sseReplacer.Replace(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + sseReplacer.Replace(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString(fmt.Sprintf("retry: %d\n", e.Retry))
+	}
+	var data string
+	switch v := e.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(bytes)
+	}
+	for _, line := range strings.Split(sseLineReplacer.Replace(data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// SSEComment 写出一行注释，客户端会忽略它，常用于保持连接
+func SSEComment(w io.Writer, comment string) error {
+	_, err := io.WriteString(w, ": "+sseReplacer.Replace(comment)+"\n\n")
+	return err
+}
@@ -0,0 +1,17 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+)
+
+type XML struct {
+	Data interface{}
+}
+
+func (r XML) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "application/xml; charset=utf-8", func(w io.Writer) error {
+		return xml.NewEncoder(w).Encode(r.Data)
+	})
+}
@@ -0,0 +1,228 @@
+package render
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type YAML struct {
+	Data interface{}
+}
+
+func (r YAML) Render(w http.ResponseWriter, code int) error {
+	return writeBuffered(w, code, "application/x-yaml; charset=utf-8", func(w io.Writer) error {
+		data, err := MarshalYAML(r.Data)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
+
+// MarshalYAML 是一个简单的 YAML 编码器，只输出块格式，足够用来渲染响应
+// 结构体字段使用 yaml 标签，没有标签时使用小写的字段名，支持 omitempty 和 "-"
+func MarshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	e := yamlEncoder{buf: &buf}
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type yamlEncoder struct {
+	buf *bytes.Buffer
+}
+
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	plainYAMLString   = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_ ./\-]*$`)
+)
+
+// encode 输出一个块，标量直接输出一行
+func (e *yamlEncoder) encode(v reflect.Value, indent int) error {
+	v = indirect(v)
+	if fields, ok := e.fields(v); ok {
+		if len(fields) == 0 {
+			e.buf.WriteString("{}\n")
+			return nil
+		}
+		for _, f := range fields {
+			e.writeIndent(indent)
+			e.buf.WriteString(quoteYAML(f.key) + ":")
+			if err := e.encodeValue(f.value, indent); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if isYAMLList(v) {
+		if v.Len() == 0 {
+			e.buf.WriteString("[]\n")
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			e.writeIndent(indent)
+			e.buf.WriteString("-")
+			if err := e.encodeValue(v.Index(i), indent); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s, err := scalarYAML(v)
+	if err != nil {
+		return err
+	}
+	e.buf.WriteString(s + "\n")
+	return nil
+}
+
+// encodeValue 输出 key: 或 - 之后的部分，非空的 map、结构体和列表另起一行缩进
+func (e *yamlEncoder) encodeValue(v reflect.Value, indent int) error {
+	v = indirect(v)
+	if fields, ok := e.fields(v); (ok && len(fields) > 0) || (isYAMLList(v) && v.Len() > 0) {
+		e.buf.WriteString("\n")
+		return e.encode(v, indent+2)
+	}
+	e.buf.WriteString(" ")
+	return e.encode(v, indent)
+}
+
+func (e *yamlEncoder) writeIndent(n int) {
+	e.buf.WriteString(strings.Repeat(" ", n))
+}
+
+// fields 返回 map 或结构体的键值对，第二个返回值表示 v 是否是 map 或结构体
+func (e *yamlEncoder) fields(v reflect.Value) ([]yamlField, bool) {
+	if !v.IsValid() || v.Type().Implements(textMarshalerType) {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		fields := make([]yamlField, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			fields = append(fields, yamlField{key: key, value: iter.Value()})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+		return fields, true
+	case reflect.Struct:
+		return structFields(v), true
+	}
+	return nil, false
+}
+
+func structFields(v reflect.Value) []yamlField {
+	fields := make([]yamlField, 0, v.NumField())
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.IndexByte(tag, ','); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		if field.Anonymous && name == "" {
+			if inner := indirect(fv); inner.Kind() == reflect.Struct {
+				fields = append(fields, structFields(inner)...)
+			}
+			continue
+		}
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, yamlField{key: name, value: fv})
+	}
+	return fields
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if v.Type().Implements(textMarshalerType) {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isYAMLList(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		return v.Type().Elem().Kind() != reflect.Uint8 && !v.IsNil()
+	case reflect.Array:
+		return true
+	}
+	return false
+}
+
+func scalarYAML(v reflect.Value) (string, error) {
+	if !v.IsValid() || ((v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface ||
+		v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil()) {
+		return "null", nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return quoteYAML(string(text)), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return quoteYAML(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		// []byte 按字符串输出
+		return quoteYAML(string(v.Bytes())), nil
+	}
+	return "", fmt.Errorf("render: unsupported yaml type %s", v.Type())
+}
+
+// quoteYAML 对可能被解析成其他类型或含有特殊字符的字符串加引号
+func quoteYAML(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if !plainYAMLString.MatchString(s) || strings.HasSuffix(s, " ") {
+		return strconv.Quote(s)
+	}
+	return s
+}
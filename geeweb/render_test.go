@@ -0,0 +1,104 @@
+package geeweb
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderErrorIsClean500(t *testing.T) {
+	e := New()
+	e.GET("/bad", func(ctx *Context) {
+		ctx.JSON(http.StatusOK, H{"ch": make(chan int)})
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bad", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != "Internal Server Error\n" {
+		t.Fatalf("expect clean 500, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	e := New()
+	e.GET("/user", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:    struct{ Name string }{"geektutu"},
+		})
+	})
+	cases := []struct {
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json; charset=utf-8"},
+		{"application/xml;q=0.9, application/x-yaml", http.StatusOK, "application/x-yaml; charset=utf-8"},
+		{"text/html, application/*;q=0.5", http.StatusOK, "application/json; charset=utf-8"},
+		{"text/html", http.StatusNotAcceptable, "text/plain; charset=utf-8"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/user", nil)
+		req.Header.Set("Accept", c.accept)
+		e.ServeHTTP(w, req)
+		if w.Code != c.code || w.Header().Get("Content-Type") != c.contentType {
+			t.Fatalf("Accept %q got %d %q", c.accept, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestSetTemplateFunc(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.tmpl"), []byte(`{{greet}} {{.}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := New()
+	e.SetFuncMap(template.FuncMap{"greet": func() string { return "hello" }})
+	e.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	e.GET("/", func(ctx *Context) { ctx.HTML(http.StatusOK, "hello.tmpl", "geektutu") })
+	e.GET("/custom", func(ctx *Context) {
+		ctx.SetTemplateFunc("greet", func() string { return "hi" })
+		ctx.HTML(http.StatusOK, "hello.tmpl", "geektutu")
+	})
+
+	// 先执行原模板，之后依然可以为单个请求替换函数
+	for _, c := range []struct{ path, body string }{
+		{"/", "hello geektutu"}, {"/custom", "hi geektutu"}, {"/", "hello geektutu"},
+	} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != c.body {
+			t.Fatalf("%s: expect %q, got %d %q", c.path, c.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestCloneTemplate(t *testing.T) {
+	base := template.Must(template.New("").Funcs(template.FuncMap{"greet": func() string { return "hello" }}).Parse(
+		`{{define "page"}}{{if .}}{{template "header" .}}{{end}}<p>{{greet}}</p>{{end}}` +
+			`{{define "header"}}<h1>{{.}}</h1>{{end}}` +
+			`{{define "other"}}other{{end}}`))
+	clone, err := cloneTemplate(base, "page", template.FuncMap{"greet": func() string { return "hi" }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(clone.Templates()); n != 2 {
+		t.Fatalf("only page and header should be cloned, got %d templates", n)
+	}
+	var buf strings.Builder
+	if err := clone.ExecuteTemplate(&buf, "page", "<geektutu>"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "<h1>&lt;geektutu&gt;</h1><p>hi</p>" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	// 转义修改的是复制的语法树，原来的模板不受影响
+	buf.Reset()
+	if err := base.ExecuteTemplate(&buf, "page", "<geektutu>"); err != nil || buf.String() != "<h1>&lt;geektutu&gt;</h1><p>hello</p>" {
+		t.Fatalf("base template changed: %q %v", buf.String(), err)
+	}
+}
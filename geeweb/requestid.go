@@ -0,0 +1,65 @@
+package geeweb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDKey 是请求 ID 在 Context.Keys 中的键
+const RequestIDKey = "geeweb.request_id"
+
+type RequestIDConfig struct {
+	// Header 默认为 X-Request-ID
+	Header string
+	// Generator 默认生成 32 位十六进制随机字符串
+	Generator func() string
+}
+
+// RequestID 优先使用请求中的 X-Request-ID，没有或不合法时生成新的，并在响应中返回
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+func RequestIDWithConfig(conf RequestIDConfig) HandlerFunc {
+	header := conf.Header
+	if header == "" {
+		header = "X-Request-ID"
+	}
+	generator := conf.Generator
+	if generator == nil {
+		generator = newRequestID
+	}
+	return func(ctx *Context) {
+		id := ctx.Request.Header.Get(header)
+		if !validRequestID(id) {
+			id = generator()
+		}
+		ctx.Set(RequestIDKey, id)
+		ctx.SetHeader(header, id)
+		ctx.Next()
+	}
+}
+
+// RequestID 返回 RequestID 中间件设置的请求 ID
+func (c *Context) RequestID() string {
+	return c.GetString(RequestIDKey)
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID 拒绝过长或含有不可见字符的 ID，防止日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package geeweb
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+const noWritten = -1
+
+// ResponseWriter 记录状态码和写入的字节数，中间件可以在 Next 之后读取
+// WriteHeader 只记录状态码，直到第一次 Write、Flush 或者处理链结束时才真正写出头部
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+	io.ReaderFrom
+
+	// Status 返回响应的状态码，未设置时为 200
+	Status() int
+	// Size 返回已经写入响应体的字节数，未写出头部时为 -1
+	Size() int
+	// Written 表示头部是否已经写出
+	Written() bool
+	// WriteHeaderNow 立即写出头部
+	WriteHeaderNow()
+	// Unwrap 返回被包装的 http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	size   int
+	status int
+	// statusCode 指向 Context.StatusCode，状态码变化时同步
+	statusCode *int
+}
+
+func (w *responseWriter) setStatus(code int) {
+	w.status = code
+	if w.statusCode != nil {
+		*w.statusCode = code
+	}
+}
+
+var _ ResponseWriter = (*responseWriter)(nil)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.size = noWritten
+	w.setStatus(http.StatusOK)
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code <= 0 || code == w.status {
+		return
+	}
+	if w.Written() {
+		log.Printf("[geeweb] WARNING: headers were already written, wanted to override status code %d with %d", w.status, code)
+		return
+	}
+	w.setStatus(code)
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	w.WriteHeaderNow()
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		// 隐藏 ReadFrom 方法，避免 io.Copy 再次调用自己
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.size += int(n)
+	return
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 之后连接交给调用者管理，不会再写出头部
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("geeweb: response does not implement http.Hijacker")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hijacker.Hijack()
+}
+
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
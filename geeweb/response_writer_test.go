@@ -0,0 +1,64 @@
+package geeweb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriterTracksStatus(t *testing.T) {
+	w := &responseWriter{}
+	recorder := httptest.NewRecorder()
+	w.reset(recorder)
+	if w.Written() || w.Status() != http.StatusOK || w.Size() != -1 {
+		t.Fatal("unexpected initial state")
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusAccepted)
+	if w.Written() || w.Status() != http.StatusAccepted {
+		t.Fatal("WriteHeader should only record the status until the body is written")
+	}
+	_, _ = w.Write([]byte("hello"))
+	_, _ = w.ReadFrom(strings.NewReader(" world"))
+	w.WriteHeader(http.StatusInternalServerError)
+	if recorder.Code != http.StatusAccepted || w.Status() != http.StatusAccepted {
+		t.Fatalf("status should not change after written, got %d", recorder.Code)
+	}
+	if w.Size() != len("hello world") || recorder.Body.String() != "hello world" {
+		t.Fatalf("unexpected size %d", w.Size())
+	}
+	if err := w.Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("recorder does not support push, got %v", err)
+	}
+	if _, _, err := w.Hijack(); err == nil {
+		t.Fatal("recorder does not support hijack")
+	}
+}
+
+func TestStatusFromHandlerWritingDirectly(t *testing.T) {
+	e := New()
+	var status, size int
+	e.Use(func(ctx *Context) {
+		ctx.Next()
+		status, size = ctx.Response.Status(), ctx.Response.Size()
+	})
+	e.GET("/teapot", func(ctx *Context) {
+		http.Error(ctx.Response, "teapot", http.StatusTeapot)
+	})
+	e.GET("/empty", func(ctx *Context) {
+		ctx.AbortWithStatus(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/teapot", nil))
+	if status != http.StatusTeapot || size != len("teapot\n") || w.Code != http.StatusTeapot {
+		t.Fatalf("got status %d size %d", status, size)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	if status != http.StatusNoContent || w.Code != http.StatusNoContent {
+		t.Fatalf("status without body should be written at the end, got %d", w.Code)
+	}
+}
@@ -1,91 +1,203 @@
 package geeweb
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
+type nodeType uint8
+
+const (
+	static   nodeType = iota // 普通字符串
+	param                    // :name
+	catchAll                 // *name
+)
+
+// node 是压缩前缀树（radix tree）的节点
+// 静态节点之间共享前缀，:param 与 *wildcard 各自单独成为一个节点，且只能出现在完整的路径段上
 type node struct {
-	pattern  string
-	part     string
-	children []*node
-	isWaild  bool
+	pattern  string // 注册的完整路由，只有终结节点不为空
+	part     string // 静态节点为压缩后的前缀，动态节点为 :name 或 *name
+	nType    nodeType
+	handlers []HandlerFunc // 注册时已经合并好的中间件和 handler
+	indices  string        // 静态子节点 part 的首字母，与 children 前 len(indices) 个节点一一对应
+	children []*node       // 静态子节点在前，之后依次是至多一个 param 子节点和一个 catchAll 子节点
 }
 
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part {
+func (n *node) staticChild(c byte) *node {
+	if i := strings.IndexByte(n.indices, c); i >= 0 {
+		return n.children[i]
+	}
+	return nil
+}
+
+func (n *node) dynamicChild(typ nodeType) *node {
+	for _, child := range n.children[len(n.indices):] {
+		if child.nType == typ {
 			return child
 		}
 	}
 	return nil
 }
 
-func (n *node) matchChildren(part string) []*node {
-	children := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWaild {
-			children = append(children, child)
+func (n *node) addStaticChild(child *node) {
+	i := len(n.indices)
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+	n.indices += string(child.part[0])
+}
+
+// addDynamicChild 保证 param 子节点排在 catchAll 子节点之前
+func (n *node) addDynamicChild(child *node) {
+	n.children = append(n.children, child)
+	if last := len(n.children) - 1; child.nType == param && last > len(n.indices) {
+		n.children[last-1], n.children[last] = n.children[last], n.children[last-1]
+	}
+}
+
+// splitPattern 把路由切分成静态片段和动态片段，如 /hello/:name/x => ["/hello/", ":name", "/x"]
+func splitPattern(pattern string) []string {
+	segments := make([]string, 0)
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		if (pattern[i] == ':' || pattern[i] == '*') && i > 0 && pattern[i-1] == '/' {
+			if start < i {
+				segments = append(segments, pattern[start:i])
+			}
+			end := strings.IndexByte(pattern[i:], '/')
+			if end < 0 {
+				end = len(pattern) - i
+			}
+			segments = append(segments, pattern[i:i+end])
+			start = i + end
+			i = start - 1
 		}
 	}
-	return children
+	if start < len(pattern) {
+		segments = append(segments, pattern[start:])
+	}
+	return segments
 }
 
-func (n *node) insert(parts []string) *node {
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// insertStatic 沿着静态节点插入 s，必要时拆分已有节点，返回 s 结尾处的节点
+func (n *node) insertStatic(s string) *node {
+	for len(s) > 0 {
+		child := n.staticChild(s[0])
+		if child == nil {
+			child = &node{part: s, nType: static}
+			n.addStaticChild(child)
+			return child
+		}
+		i := longestCommonPrefix(child.part, s)
+		if i < len(child.part) {
+			// 拆分节点：原节点保留公共前缀，其余部分（包括子节点和路由）下沉到新节点
+			tail := *child
+			tail.part = child.part[i:]
+			*child = node{
+				part:     child.part[:i],
+				nType:    static,
+				indices:  string(tail.part[0]),
+				children: []*node{&tail},
+			}
+		}
+		n, s = child, s[i:]
+	}
+	return n
+}
+
+func (n *node) insertDynamic(part string, pattern string) *node {
+	typ := param
+	if part[0] == '*' {
+		typ = catchAll
+	}
+	if len(part) == 1 {
+		panic(fmt.Sprintf("geeweb: wildcard %q in route %q must be named", part, pattern))
+	}
+	if child := n.dynamicChild(typ); child != nil {
+		if child.part != part {
+			panic(fmt.Sprintf("geeweb: %q in route %q conflicts with existing wildcard %q", part, pattern, child.part))
+		}
+		return child
+	}
+	child := &node{part: part, nType: typ}
+	n.addDynamicChild(child)
+	return child
+}
+
+func (n *node) insert(pattern string) *node {
 	m := n
-	for _, part := range parts {
-		tmp := m.matchChild(part)
-		if tmp == nil {
-			newNode := &node{
-				part:     part,
-				children: make([]*node, 0),
-				isWaild:  part[0] == ':' || part[0] == '*',
+	for _, segment := range splitPattern(pattern) {
+		switch segment[0] {
+		case ':', '*':
+			m = m.insertDynamic(segment, pattern)
+		default:
+			if m.nType == catchAll {
+				panic(fmt.Sprintf("geeweb: catch-all must be the last segment in route %q", pattern))
 			}
-			m.children = append(m.children, newNode)
-			m = newNode
-		} else {
-			m = tmp
+			m = m.insertStatic(segment)
 		}
 	}
-	m.pattern = "/" + strings.Join(parts, "/")
-	if m.pattern == "/" {
+	if m.pattern != "" {
+		panic(fmt.Sprintf("geeweb: route %q conflicts with existing route %q", pattern, m.pattern))
 	}
+	m.pattern = pattern
 	return m
 }
 
-func (n *node) search(parts []string) *node {
-	// 如果没有插入 / 则 root.pattern 为空，此时查询 / 应返回 nil
-	if len(parts) == 0 && n.pattern == "/" {
-		return n
-	}
-	// 广度优先搜索
-	m := make([]*node, 1)
-	m[0] = n
-	head := 0
-	for k, p := range parts {
-		m = m[head:]
-		head = len(m)
-		for i := 0; i < head; i++ {
-			children := m[i].matchChildren(p)
-			// k + 1 == len 代表刚刚好匹配
-			if len(children) > 0 && (k+1 == len(parts) || strings.HasPrefix(children[0].part, "*")) {
-				return children[0]
+// search 在 n 已经匹配的前提下继续匹配剩余的 path
+// 优先级依次为 静态 > :param > *wildcard，某一分支匹配失败时回溯到下一优先级
+func (n *node) search(path string, params map[string]string) *node {
+	if path == "" {
+		if n.pattern != "" {
+			return n
+		}
+		return nil
+	}
+	if child := n.staticChild(path[0]); child != nil && strings.HasPrefix(path, child.part) {
+		if m := child.search(path[len(child.part):], params); m != nil {
+			return m
+		}
+	}
+	if child := n.dynamicChild(param); child != nil {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			key := child.part[1:]
+			params[key] = path[:end]
+			if m := child.search(path[end:], params); m != nil {
+				return m
 			}
-			m = append(m, children...)
+			delete(params, key)
 		}
 	}
+	if child := n.dynamicChild(catchAll); child != nil {
+		params[child.part[1:]] = path
+		return child
+	}
 	return nil
 }
 
 type router struct {
-	roots   map[string]*node
-	handler map[string]HandlerFunc
+	roots  map[string]*node
+	groups []*RouterGroup // 注册到这棵路由树的分组，第一个是根分组
 }
 
 func newRouter() *router {
 	return &router{
-		roots:   make(map[string]*node),
-		handler: make(map[string]HandlerFunc),
+		roots: make(map[string]*node),
 	}
 }
 
@@ -107,19 +219,22 @@ func parsePattern(pattern string) []string {
 	return parts[:i]
 }
 
-func (r *router) addRoute(method, pattern string, f HandlerFunc) {
-	if _, ok := r.roots[method]; !ok {
-		r.roots[method] = &node{
-			pattern:  "",
-			part:     "/", // 这个其实无所谓
-			children: make([]*node, 0),
-			isWaild:  false,
-		}
+// cleanPath 去掉多余的 / 和结尾的 /，与 parsePattern 对路由的处理保持一致
+func cleanPath(path string) string {
+	if path != "" && path[0] == '/' && !strings.Contains(path, "//") &&
+		(len(path) == 1 || path[len(path)-1] != '/') {
+		return path
 	}
-	keyNode := r.roots[method].insert(parsePattern(pattern))
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+	return "/" + strings.Join(parts, "/")
+}
 
-	key := method + "-" + keyNode.pattern
-	r.handler[key] = f
+func (r *router) addRoute(method, pattern string, handlers []HandlerFunc) {
+	if _, ok := r.roots[method]; !ok {
+		r.roots[method] = &node{nType: static}
+	}
+	keyNode := r.roots[method].insert("/" + strings.Join(parsePattern(pattern), "/"))
+	keyNode.handlers = handlers
 }
 
 func (r *router) getRoute(method string, path string) (*node, map[string]string) {
@@ -127,35 +242,83 @@ func (r *router) getRoute(method string, path string) (*node, map[string]string)
 	if !ok {
 		return nil, nil
 	}
-	originParts := parsePattern(path)
-	keyNode := root.search(originParts)
-	if keyNode != nil {
-		params := make(map[string]string)
-		parts := parsePattern(keyNode.pattern)
-		for i, v := range parts {
-			if v[0] == ':' {
-				params[v[1:]] = originParts[i]
-			}
-			if v[0] == '*' {
-				params[v[1:]] = strings.Join(originParts[i:], "/")
-				break
-			}
-		}
+	params := make(map[string]string)
+	if keyNode := root.search(cleanPath(path), params); keyNode != nil {
 		return keyNode, params
 	}
 	return nil, nil
 }
 
-func (r *router) handle(ctx *Context) {
-	keyNode, params := r.getRoute(ctx.Method, ctx.Path)
-	if keyNode != nil {
-		ctx.Params = params
-		handler := r.handler[ctx.Method+"-"+keyNode.pattern]
-		ctx.handlers = append(ctx.handlers, handler)
-	} else {
-		ctx.handlers = append(ctx.handlers, func(ctx *Context) {
-			ctx.String(http.StatusNotFound, "Page %v Not Found!", ctx.Path)
-		})
+// allowed 返回 path 在各个方法下的注册情况，用于 OPTIONS 和 405 响应的 Allow 头
+func (r *router) allowed(path string) []string {
+	allow := make([]string, 0)
+	hasGet, hasHead := false, false
+	for method := range r.roots {
+		if method == http.MethodOptions {
+			continue
+		}
+		if keyNode, _ := r.getRoute(method, path); keyNode != nil {
+			allow = append(allow, method)
+			hasGet = hasGet || method == http.MethodGet
+			hasHead = hasHead || method == http.MethodHead
+		}
+	}
+	if len(allow) == 0 {
+		return allow
+	}
+	// 这个路径上 HEAD 没有单独注册时会复用 GET
+	if hasGet && !hasHead {
+		allow = append(allow, http.MethodHead)
+	}
+	allow = append(allow, http.MethodOptions)
+	sort.Strings(allow)
+	return allow
+}
+
+func optionsHandler(ctx *Context) {
+	ctx.Status(http.StatusNoContent)
+}
+
+func methodNotAllowedHandler(ctx *Context) {
+	ctx.String(http.StatusMethodNotAllowed, "Method %v Not Allowed!", ctx.Method)
+}
+
+func notFoundHandler(ctx *Context) {
+	ctx.String(http.StatusNotFound, "Page %v Not Found!", ctx.Path)
+}
+
+// handle 中 hostParams 是 Host 中的参数，与路由参数同名时以路由参数为准
+func (r *router) handle(ctx *Context, hostParams map[string]string) {
+	method := ctx.Method
+	keyNode, params := r.getRoute(method, ctx.Path)
+	if keyNode == nil && method == http.MethodHead {
+		// HEAD 没有单独注册时复用 GET，响应体由 net/http 丢弃
+		method = http.MethodGet
+		keyNode, params = r.getRoute(method, ctx.Path)
+	}
+	// 自动的 OPTIONS 执行前缀匹配的最深分组的中间件，比如分组上的 CORS，404 和 405 见 missHandlers
+	if keyNode == nil {
+		ctx.Params = hostParams
+		if allow := r.allowed(ctx.Path); len(allow) > 0 {
+			ctx.SetHeader("Allow", strings.Join(allow, ", "))
+			if ctx.Method == http.MethodOptions {
+				ctx.handlers = r.groupFor(ctx.Path).combineHandlers([]HandlerFunc{optionsHandler})
+			} else {
+				ctx.handlers = r.missHandlers(ctx.Path, true)
+			}
+		} else {
+			ctx.handlers = r.missHandlers(ctx.Path, false)
+		}
+		ctx.Next()
+		return
+	}
+	for key, value := range hostParams {
+		if _, ok := params[key]; !ok {
+			params[key] = value
+		}
 	}
+	ctx.Params = params
+	ctx.fullPath = keyNode.pattern
+	ctx.handlers = keyNode.handlers
 	ctx.Next()
 }
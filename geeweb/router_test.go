@@ -2,6 +2,8 @@ package geeweb
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -67,3 +69,131 @@ func TestGetRoute(t *testing.T) {
 	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["filepath"])
 
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	e := New()
+	e.GET("/users/:id", func(ctx *Context) { ctx.String(http.StatusOK, "get %s", ctx.Param("id")) })
+	e.PUT("/users/:id", func(ctx *Context) { ctx.String(http.StatusOK, "put %s", ctx.Param("id")) })
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/users/1", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "put 1" {
+		t.Fatalf("PUT /users/1 got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE /users/1 should be 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, PUT" {
+		t.Fatalf("unexpected Allow header %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("DELETE /posts/1 should be 404, got %d", w.Code)
+	}
+}
+
+func TestAutoHeadAndOptions(t *testing.T) {
+	e := New()
+	e.GET("/hello", func(ctx *Context) { ctx.String(http.StatusOK, "hello") })
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/hello", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("HEAD /hello should fall back to GET, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/hello", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("OPTIONS /hello got %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	// 其他路径注册了 HEAD 之后，/hello 依然可以通过 GET 响应 HEAD
+	e.HEAD("/other", func(ctx *Context) {})
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/hello", nil))
+	if w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("HEAD should still be allowed on /hello, got Allow %q", w.Header().Get("Allow"))
+	}
+
+	e.OPTIONS("/hello", func(ctx *Context) { ctx.String(http.StatusOK, "custom") })
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/hello", nil))
+	if w.Body.String() != "custom" {
+		t.Fatalf("registered OPTIONS handler should win, got %q", w.Body.String())
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	patterns := []string{"/hello/:name", "/hello/b/c", "/hello/*path", "/hello/b/:x/d"}
+	cases := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/hello/b/c", "/hello/b/c", map[string]string{}},
+		{"/hello/b", "/hello/:name", map[string]string{"name": "b"}},
+		{"/hello/geektutu", "/hello/:name", map[string]string{"name": "geektutu"}},
+		{"/hello/b/e/d", "/hello/b/:x/d", map[string]string{"x": "e"}},
+		{"/hello/b/e/f", "/hello/*path", map[string]string{"path": "b/e/f"}},
+		{"/hello/b/c/", "/hello/b/c", map[string]string{}},
+	}
+	// 无论注册顺序如何，匹配结果都应该一致
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}} {
+		r := newRouter()
+		for _, i := range order {
+			r.addRoute("GET", patterns[i], nil)
+		}
+		for _, c := range cases {
+			n, ps := r.getRoute("GET", c.path)
+			if n == nil || n.pattern != c.pattern || !reflect.DeepEqual(ps, c.params) {
+				t.Fatalf("order %v: %s should match %s %v, got %v %v", order, c.path, c.pattern, c.params, n, ps)
+			}
+		}
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	cases := [][]string{
+		{"/users/:id", "/users/:name"},
+		{"/users/:id", "/users/:id/"},
+		{"/assets/*filepath", "/assets/*file"},
+		{"/users/:id/books", "/users/:name/cars"},
+	}
+	for _, c := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("registering %v should panic", c)
+				}
+			}()
+			r := newRouter()
+			for _, pattern := range c {
+				r.addRoute("GET", pattern, nil)
+			}
+		}()
+	}
+}
+
+func TestFullPath(t *testing.T) {
+	e := New()
+	var got string
+	e.Use(func(ctx *Context) { got = ctx.FullPath() })
+	e.GET("/users/:id", func(ctx *Context) {})
+	e.GET("/assets/*filepath", func(ctx *Context) {})
+	for path, expect := range map[string]string{
+		"/users/42":         "/users/:id",
+		"/assets/js/app.js": "/assets/*filepath",
+		"/unknown":          "",
+	} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got != expect {
+			t.Fatalf("%s: expect full path %q, got %q", path, expect, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package geeweb
+
+import (
+	"fmt"
+	"gee/render"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// RouteInfo 描述一条注册的路由
+type RouteInfo struct {
+	// Host 是 Engine.Host 注册的主机名，默认路由树中为空
+	Host   string `json:"host,omitempty"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Handler 是最后一个 handler 的函数名
+	Handler string `json:"handler"`
+	// Middlewares 是 handler 之前的中间件数量，包括所在分组及其父分组的中间件
+	Middlewares int         `json:"middlewares"`
+	HandlerFunc HandlerFunc `json:"-"`
+}
+
+// Routes 返回所有注册的路由，按主机、路径和方法排序，默认路由树的路由在前
+func (e *Engine) Routes() []RouteInfo {
+	routes := e.router.routes("", nil)
+	for _, h := range e.hosts {
+		routes = h.router.routes(h.pattern, routes)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func (r *router) routes(host string, routes []RouteInfo) []RouteInfo {
+	for method, root := range r.roots {
+		root.walk(func(n *node) {
+			if n.pattern == "" || len(n.handlers) == 0 {
+				return
+			}
+			last := n.handlers[len(n.handlers)-1]
+			routes = append(routes, RouteInfo{
+				Host:        host,
+				Method:      method,
+				Path:        n.pattern,
+				Handler:     nameOfFunction(last),
+				Middlewares: len(n.handlers) - 1,
+				HandlerFunc: last,
+			})
+		})
+	}
+	return routes
+}
+
+func (n *node) walk(f func(n *node)) {
+	f(n)
+	for _, child := range n.children {
+		child.walk(f)
+	}
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// writeRoutes 按 方法 路径 --> handler (中间件数) 的格式写出路由表，Host 的路由在路径前加上主机名
+func writeRoutes(w io.Writer, routes []RouteInfo) {
+	for _, r := range routes {
+		fmt.Fprintf(w, "%-7s %-30s --> %s (%d handlers)\n", r.Method, r.Host+r.Path, r.Handler, r.Middlewares+1)
+	}
+}
+
+// debugPrintRoutes 在 DebugMode 下启动服务时打印路由表
+func (e *Engine) debugPrintRoutes() {
+	if !IsDebugging() {
+		return
+	}
+	var b strings.Builder
+	writeRoutes(&b, e.Routes())
+	log.Printf("[geeweb-debug] routes:\n%s", b.String())
+}
+
+var routesTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Routes</title></head>
+<body><table border="1" cellpadding="4">
+<tr><th>Host</th><th>Method</th><th>Path</th><th>Handler</th><th>Middlewares</th></tr>
+{{range .}}<tr><td>{{.Host}}</td><td>{{.Method}}</td><td>{{.Path}}</td><td>{{.Handler}}</td><td>{{.Middlewares}}</td></tr>
+{{end}}</table></body></html>`))
+
+// RoutesHandler 返回路由表，根据 Accept 返回 JSON、HTML 或纯文本，可以挂载到受保护的分组上，
+// 比如 admin.GET("/debug/routes", engine.RoutesHandler())
+func (e *Engine) RoutesHandler() HandlerFunc {
+	return func(ctx *Context) {
+		routes := e.Routes()
+		switch ctx.NegotiateFormat(MIMEJSON, MIMEHTML, MIMEPlain) {
+		case MIMEHTML:
+			ctx.Render(http.StatusOK, render.HTML{Template: routesTemplate, Data: routes})
+		case MIMEPlain:
+			var b strings.Builder
+			writeRoutes(&b, routes)
+			ctx.String(http.StatusOK, "%s", b.String())
+		default:
+			ctx.JSON(http.StatusOK, routes)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package geeweb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func listUsers(ctx *Context) {}
+
+func TestRoutes(t *testing.T) {
+	e := New()
+	e.Use(Recovery())
+	v1 := e.Group("/v1")
+	v1.Use(func(ctx *Context) {})
+	v1.GET("/users", listUsers)
+	v1.POST("/users/:id", func(ctx *Context) {})
+	e.GET("/assets/*filepath", func(ctx *Context) {})
+	e.GET("/debug/routes", e.RoutesHandler())
+
+	routes := e.Routes()
+	expect := []struct {
+		method, path string
+		middlewares  int
+	}{
+		{"GET", "/assets/*filepath", 1},
+		{"GET", "/debug/routes", 1},
+		{"GET", "/v1/users", 2},
+		{"POST", "/v1/users/:id", 2},
+	}
+	if len(routes) != len(expect) {
+		t.Fatalf("expect %d routes, got %+v", len(expect), routes)
+	}
+	for i, r := range routes {
+		if r.Method != expect[i].method || r.Path != expect[i].path || r.Middlewares != expect[i].middlewares {
+			t.Fatalf("route %d: expect %+v, got %+v", i, expect[i], r)
+		}
+	}
+	if routes[2].Handler != "gee.listUsers" {
+		t.Fatalf("unexpected handler name %q", routes[2].Handler)
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	var got []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got) != len(expect) {
+		t.Fatalf("expect JSON route table, got %q", w.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/routes", nil)
+	req.Header.Set("Accept", "text/html")
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "<td>/v1/users/:id</td>") {
+		t.Fatalf("expect HTML route table, got %q", w.Body.String())
+	}
+}
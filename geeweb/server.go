@@ -0,0 +1,160 @@
+package geeweb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ServerConfig 对应 http.Server 中的超时等配置，零值表示不限制
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	TLSConfig         *tls.Config
+}
+
+// Server 返回 Engine 使用的 http.Server，第一次调用时创建，可以在 Run 之前直接修改
+func (e *Engine) Server() *http.Server {
+	e.serverMu.Lock()
+	defer e.serverMu.Unlock()
+	if e.server == nil {
+		e.server = &http.Server{Handler: e}
+	}
+	return e.server
+}
+
+func (e *Engine) SetServerConfig(cfg ServerConfig) {
+	srv := e.Server()
+	srv.ReadTimeout = cfg.ReadTimeout
+	srv.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+	srv.WriteTimeout = cfg.WriteTimeout
+	srv.IdleTimeout = cfg.IdleTimeout
+	srv.MaxHeaderBytes = cfg.MaxHeaderBytes
+	srv.TLSConfig = cfg.TLSConfig
+}
+
+// OnShutdown 注册的函数在 Shutdown 等待所有请求处理完之后按注册顺序执行
+func (e *Engine) OnShutdown(f func()) {
+	e.serverMu.Lock()
+	defer e.serverMu.Unlock()
+	e.shutdownHooks = append(e.shutdownHooks, f)
+}
+
+// servingListener 记录 http.Server 是否开始 Accept，Shutdown 之后调用的 Serve 不会 Accept
+type servingListener struct {
+	net.Listener
+	serving int32
+}
+
+func (l *servingListener) Accept() (net.Conn, error) {
+	atomic.StoreInt32(&l.serving, 1)
+	return l.Listener.Accept()
+}
+
+// serve 把 Shutdown 引起的 http.ErrServerClosed 视为正常退出，
+// 但是从未开始服务（比如 Shutdown 之后才调用 Run）时依然返回 http.ErrServerClosed
+func serve(l net.Listener, fn func(net.Listener) error) error {
+	sl := &servingListener{Listener: l}
+	err := fn(sl)
+	if errors.Is(err, http.ErrServerClosed) && atomic.LoadInt32(&sl.serving) == 1 {
+		return nil
+	}
+	return err
+}
+
+// Run 在 Shutdown 开始时立即返回 nil，调用者应等待 Shutdown 返回后再退出
+func (e *Engine) Run(addr string) error {
+	e.debugPrintRoutes()
+	log.Printf("Gee Start! Listen Request on %v", addr)
+	srv := e.Server()
+	srv.Addr = addr
+	if addr == "" {
+		addr = ":http"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return serve(l, srv.Serve)
+}
+
+func (e *Engine) RunTLS(addr, certFile, keyFile string) error {
+	e.debugPrintRoutes()
+	log.Printf("Gee Start! Listen HTTPS Request on %v", addr)
+	srv := e.Server()
+	srv.Addr = addr
+	if addr == "" {
+		addr = ":https"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return serve(l, func(l net.Listener) error {
+		return srv.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+func (e *Engine) RunListener(l net.Listener) error {
+	e.debugPrintRoutes()
+	log.Printf("Gee Start! Listen Request on %v", l.Addr())
+	return serve(l, e.Server().Serve)
+}
+
+// RunUnix 监听 unix socket，已存在的 socket 文件会被删除
+func (e *Engine) RunUnix(file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return e.RunListener(l)
+}
+
+// Shutdown 停止接收新连接，等待正在处理的请求结束后执行 OnShutdown 注册的函数
+// 被 Hijack 的连接（如 WebSocket）不会被等待，需要在 OnShutdown 中自行关闭
+func (e *Engine) Shutdown(ctx context.Context) error {
+	err := e.Server().Shutdown(ctx)
+	e.serverMu.Lock()
+	hooks := e.shutdownHooks
+	e.shutdownHooks = nil
+	e.serverMu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	return err
+}
+
+// RunGraceful 在收到 SIGINT 或 SIGTERM 之后优雅退出，最多等待 timeout
+func (e *Engine) RunGraceful(addr string, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- e.Run(addr)
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case err := <-errCh:
+		return err
+	case sig := <-quit:
+		log.Printf("Gee receive %v, shutting down...", sig)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return e.Shutdown(ctx)
+}
@@ -0,0 +1,116 @@
+package geeweb
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	e := New()
+	started := make(chan struct{})
+	e.GET("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+	var hooked bool
+	e.OnShutdown(func() { hooked = true })
+	e.SetServerConfig(ServerConfig{ReadTimeout: time.Second, MaxHeaderBytes: 1 << 10})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- e.RunListener(l) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		resCh <- result{string(body), err}
+	}()
+
+	<-started
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !hooked {
+		t.Fatal("OnShutdown hooks should run")
+	}
+	if res := <-resCh; res.err != nil || res.body != "done" {
+		t.Fatalf("in-flight request should complete, got %q %v", res.body, res.err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("RunListener should return nil after shutdown, got %v", err)
+	}
+	if e.Server().MaxHeaderBytes != 1<<10 {
+		t.Fatal("server config should be applied")
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	e := New()
+	e.GET("/ping", func(ctx *Context) { ctx.String(http.StatusOK, "pong") })
+	sock := filepath.Join(t.TempDir(), "gee.sock")
+	go func() { _ = e.RunUnix(sock) }()
+	defer e.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://unix/ping"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "pong" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestRunAfterShutdown(t *testing.T) {
+	e := New()
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Run("127.0.0.1:0"); err != http.ErrServerClosed {
+		t.Fatalf("Run after Shutdown should not look like a clean exit, got %v", err)
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	defer SetMode(Mode())
+	modeFromEnv(ReleaseMode)
+	if Mode() != ReleaseMode {
+		t.Fatalf("expect release mode, got %s", Mode())
+	}
+	modeFromEnv("prod")
+	if Mode() != DebugMode {
+		t.Fatalf("unknown mode should fall back to debug, got %s", Mode())
+	}
+}
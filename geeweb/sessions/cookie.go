@@ -0,0 +1,153 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	geeweb "gee"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCookieTooLong = errors.New("sessions: encoded cookie exceeds 4096 bytes")
+	errInvalidCookie = errors.New("sessions: invalid cookie")
+)
+
+const maxCookieSize = 4096
+
+type codec struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+// CookieStore 把会话数据签名并加密后保存在 Cookie 中，服务端不保存任何状态
+type CookieStore struct {
+	codecs []codec
+	now    func() time.Time
+}
+
+// NewCookieStore 的参数是成对的签名密钥和加密密钥，加密密钥长度为 16、24 或 32 字节，为 nil 时只签名不加密。
+// 第一对密钥用于保存，所有密钥都可以用于读取，轮换密钥时把新密钥放在最前面即可
+func NewCookieStore(keyPairs ...[]byte) (*CookieStore, error) {
+	if len(keyPairs) == 0 {
+		return nil, errors.New("sessions: at least one hash key is required")
+	}
+	s := &CookieStore{now: time.Now}
+	for i := 0; i < len(keyPairs); i += 2 {
+		c := codec{hashKey: keyPairs[i]}
+		if len(c.hashKey) == 0 {
+			return nil, errors.New("sessions: hash key must not be empty")
+		}
+		if i+1 < len(keyPairs) && keyPairs[i+1] != nil {
+			block, err := aes.NewCipher(keyPairs[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if c.aead, err = cipher.NewGCM(block); err != nil {
+				return nil, err
+			}
+		}
+		s.codecs = append(s.codecs, c)
+	}
+	return s, nil
+}
+
+func (s *CookieStore) Load(ctx *geeweb.Context, session *Session) error {
+	cookie, err := ctx.Request.Cookie(session.Name())
+	if err != nil {
+		return nil
+	}
+	for _, c := range s.codecs {
+		if values, err := c.decode(session.Name(), cookie.Value, session.Options.MaxAge, s.now()); err == nil {
+			session.Values = values
+			session.IsNew = false
+			return nil
+		}
+	}
+	// 签名不匹配或者已经过期，当作新会话
+	return nil
+}
+
+func (s *CookieStore) Save(ctx *geeweb.Context, session *Session) error {
+	if session.Options.MaxAge < 0 {
+		setCookie(ctx, session.Name(), "", session.Options)
+		return nil
+	}
+	value, err := s.codecs[0].encode(session.Name(), session.Values, s.now())
+	if err != nil {
+		return err
+	}
+	if len(value) > maxCookieSize {
+		return ErrCookieTooLong
+	}
+	setCookie(ctx, session.Name(), value, session.Options)
+	return nil
+}
+
+// encode 的结果为 时间戳|数据|签名，数据经过 AES-GCM 加密，cookie 名作为附加数据防止挪用到其他 cookie
+func (c codec) encode(name string, values map[string]interface{}, now time.Time) (string, error) {
+	data, err := encodeValues(values)
+	if err != nil {
+		return "", err
+	}
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		data = c.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+	payload := strconv.FormatInt(now.Unix(), 10) + "|" + base64.RawURLEncoding.EncodeToString(data)
+	return payload + "|" + base64.RawURLEncoding.EncodeToString(c.mac(name, payload)), nil
+}
+
+func (c codec) decode(name, value string, maxAge int, now time.Time) (map[string]interface{}, error) {
+	i := strings.LastIndexByte(value, '|')
+	if i < 0 {
+		return nil, errInvalidCookie
+	}
+	payload := value[:i]
+	mac, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || !hmac.Equal(mac, c.mac(name, payload)) {
+		return nil, errInvalidCookie
+	}
+	parts := strings.SplitN(payload, "|", 2)
+	if len(parts) != 2 {
+		return nil, errInvalidCookie
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errInvalidCookie
+	}
+	// Cookie 的 Max-Age 由浏览器控制，服务端也要检查，防止旧的 Cookie 被重放
+	if maxAge > 0 && now.Unix()-ts > int64(maxAge) {
+		return nil, errInvalidCookie
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidCookie
+	}
+	if c.aead != nil {
+		size := c.aead.NonceSize()
+		if len(data) < size {
+			return nil, errInvalidCookie
+		}
+		if data, err = c.aead.Open(nil, data[:size], data[size:], []byte(name)); err != nil {
+			return nil, errInvalidCookie
+		}
+	}
+	return decodeValues(data)
+}
+
+func (c codec) mac(name, payload string) []byte {
+	h := hmac.New(sha256.New, c.hashKey)
+	h.Write([]byte(name + "|" + payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,160 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	geeweb "gee"
+	"sync"
+	"time"
+)
+
+// ErrNotFound 由 Backend.Get 在会话不存在或已过期时返回
+var ErrNotFound = errors.New("sessions: session not found")
+
+// Backend 保存编码后的会话数据，基于 geecache 实现时 Get 可以调用 Group.Get，
+// 由 Group 的 Getter 从数据源读取，Set 和 Delete 写入数据源
+type Backend interface {
+	Get(id string) ([]byte, error)
+	Set(id string, data []byte, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// ServerStore 只在 Cookie 中保存随机的会话 ID，数据保存在 Backend 中
+type ServerStore struct {
+	backend Backend
+}
+
+func NewServerStore(backend Backend) *ServerStore {
+	return &ServerStore{backend: backend}
+}
+
+// NewMemoryStore 返回保存在内存中的会话存储，只适用于单机部署
+func NewMemoryStore() *ServerStore {
+	return NewServerStore(NewMemoryBackend())
+}
+
+func (s *ServerStore) Load(ctx *geeweb.Context, session *Session) error {
+	cookie, err := ctx.Request.Cookie(session.Name())
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	data, err := s.backend.Get(cookie.Value)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	values, err := decodeValues(data)
+	if err != nil {
+		return nil
+	}
+	session.ID = cookie.Value
+	session.Values = values
+	session.IsNew = false
+	return nil
+}
+
+func (s *ServerStore) Save(ctx *geeweb.Context, session *Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+		setCookie(ctx, session.Name(), "", session.Options)
+		return nil
+	}
+	if session.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+	data, err := encodeValues(session.Values)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if err := s.backend.Set(session.ID, data, ttl); err != nil {
+		return err
+	}
+	setCookie(ctx, session.Name(), session.ID, session.Options)
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// MemoryBackend 是带过期时间的内存存储，过期的会话在访问时或定期清理时删除
+type MemoryBackend struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (b *MemoryBackend) Get(id string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	entry, ok := b.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !entry.expires.IsZero() && now.After(entry.expires) {
+		delete(b.entries, id)
+		return nil, ErrNotFound
+	}
+	return entry.data, nil
+}
+
+// Set 的 ttl 为 0 时会话不过期，直到被删除
+func (b *MemoryBackend) Set(id string, data []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if now.Sub(b.lastSweep) > sweepInterval {
+		b.sweep(now)
+	}
+	entry := memoryEntry{data: data}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	b.entries[id] = entry
+	return nil
+}
+
+func (b *MemoryBackend) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, id)
+	return nil
+}
+
+func (b *MemoryBackend) sweep(now time.Time) {
+	b.lastSweep = now
+	for id, entry := range b.entries {
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			delete(b.entries, id)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	geeweb "gee"
+	"net/http"
+)
+
+// DefaultKey 是会话在 Context 中的 key
+const DefaultKey = "geeweb.session"
+
+const flashesKey = "_flash"
+
+func init() {
+	// Values 中的 interface{} 需要注册具体类型，闪现消息保存为 []interface{}
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Options 是会话 Cookie 的属性，MaxAge 小于 0 表示删除会话
+type Options struct {
+	Path     string
+	Domain   string
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// Store 负责读取和保存会话，会话数据可以全部保存在 Cookie 中，也可以只在 Cookie 中保存 ID
+type Store interface {
+	// Load 读取请求中名为 session.Name() 的会话，Cookie 不存在或无效时保持 session 为新会话
+	Load(ctx *geeweb.Context, session *Session) error
+	// Save 保存会话并写出 Cookie，必须在写出响应之前调用
+	Save(ctx *geeweb.Context, session *Session) error
+}
+
+// Session 在第一次访问时才从 Store 中读取，修改后需要调用 Save
+type Session struct {
+	// ID 只在服务端存储中使用
+	ID      string
+	Values  map[string]interface{}
+	Options *Options
+	IsNew   bool
+
+	name   string
+	store  Store
+	ctx    *geeweb.Context
+	loaded bool
+}
+
+// Sessions 返回会话中间件，options 为 Cookie 的默认属性，handler 中通过 Default 获取会话
+func Sessions(name string, store Store, options Options) geeweb.HandlerFunc {
+	if options.Path == "" {
+		options.Path = "/"
+	}
+	return func(ctx *geeweb.Context) {
+		opts := options
+		ctx.Set(DefaultKey, &Session{name: name, store: store, ctx: ctx, Options: &opts})
+	}
+}
+
+// Default 返回 Sessions 中间件创建的会话，没有使用中间件时 panic
+func Default(ctx *geeweb.Context) *Session {
+	return ctx.MustGet(DefaultKey).(*Session)
+}
+
+// BindContext 返回绑定到 ctx 的副本，Values 与原会话共享，用于 geeweb.Timeout 复制 Context
+func (s *Session) BindContext(ctx *geeweb.Context) interface{} {
+	cp := *s
+	cp.ctx = ctx
+	return &cp
+}
+
+func (s *Session) Name() string {
+	return s.name
+}
+
+// load 读取失败时当作新会话，错误记录到 Context 中
+func (s *Session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.Values = make(map[string]interface{})
+	s.IsNew = true
+	if err := s.store.Load(s.ctx, s); err != nil {
+		s.ctx.Error(err)
+	}
+}
+
+func (s *Session) Get(key string) interface{} {
+	s.load()
+	return s.Values[key]
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.load()
+	s.Values[key] = value
+}
+
+func (s *Session) Delete(key string) {
+	s.load()
+	delete(s.Values, key)
+}
+
+// Clear 删除所有数据，但保留会话本身
+func (s *Session) Clear() {
+	s.load()
+	s.Values = make(map[string]interface{})
+}
+
+// AddFlash 添加一条闪现消息，它在下一次被 Flashes 读取之后删除
+func (s *Session) AddFlash(value interface{}) {
+	s.load()
+	flashes, _ := s.Values[flashesKey].([]interface{})
+	s.Values[flashesKey] = append(flashes, value)
+}
+
+// Flashes 返回并删除所有闪现消息，之后需要调用 Save
+func (s *Session) Flashes() []interface{} {
+	s.load()
+	flashes, _ := s.Values[flashesKey].([]interface{})
+	delete(s.Values, flashesKey)
+	return flashes
+}
+
+func (s *Session) Save() error {
+	s.load()
+	return s.store.Save(s.ctx, s)
+}
+
+// Destroy 删除会话并让浏览器删除 Cookie
+func (s *Session) Destroy() error {
+	s.load()
+	s.Values = make(map[string]interface{})
+	s.Options.MaxAge = -1
+	return s.store.Save(s.ctx, s)
+}
+
+func encodeValues(values map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeValues(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func setCookie(ctx *geeweb.Context, name, value string, options *Options) {
+	http.SetCookie(ctx.Response, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     options.Path,
+		Domain:   options.Domain,
+		MaxAge:   options.MaxAge,
+		Secure:   options.Secure,
+		HttpOnly: options.HttpOnly,
+		SameSite: options.SameSite,
+	})
+}
@@ -0,0 +1,156 @@
+package sessions
+
+import (
+	geeweb "gee"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEngine(store Store) *geeweb.Engine {
+	e := geeweb.New()
+	e.Use(Sessions("geeweb_session", store, Options{MaxAge: 3600, HttpOnly: true}))
+	e.GET("/login", func(ctx *geeweb.Context) {
+		s := Default(ctx)
+		s.Set("user", "geektutu")
+		s.AddFlash("welcome")
+		if err := s.Save(); err != nil {
+			ctx.Fail(http.StatusInternalServerError, err.Error())
+		}
+	})
+	e.GET("/me", func(ctx *geeweb.Context) {
+		s := Default(ctx)
+		user, _ := s.Get("user").(string)
+		flashes := s.Flashes()
+		_ = s.Save()
+		ctx.String(http.StatusOK, "%s %v", user, flashes)
+	})
+	e.GET("/logout", func(ctx *geeweb.Context) {
+		_ = Default(ctx).Destroy()
+	})
+	return e
+}
+
+// do 发送带 cookie 的请求，返回响应和最新的 cookie
+func do(e *geeweb.Engine, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	req := httptest.NewRequest("GET", path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		return w, cookies[0]
+	}
+	return w, cookie
+}
+
+func testStore(t *testing.T, store Store) {
+	e := newEngine(store)
+	w, cookie := do(e, "/login", nil)
+	if cookie == nil || w.Code != http.StatusOK || !cookie.HttpOnly || cookie.MaxAge != 3600 {
+		t.Fatalf("login should set session cookie, got %d %v", w.Code, cookie)
+	}
+	w, cookie = do(e, "/me", cookie)
+	if w.Body.String() != "geektutu [welcome]" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	// 闪现消息只能读取一次
+	if w, _ = do(e, "/me", cookie); w.Body.String() != "geektutu []" {
+		t.Fatalf("flash should be consumed, got %q", w.Body.String())
+	}
+	if w, _ = do(e, "/me", nil); w.Body.String() != " []" {
+		t.Fatalf("request without cookie should get a new session, got %q", w.Body.String())
+	}
+	_, deleted := do(e, "/logout", cookie)
+	if deleted.MaxAge >= 0 {
+		t.Fatal("logout should delete the cookie")
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	store, err := NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	e := newEngine(store)
+	_, cookie := do(e, "/login", nil)
+	if strings.Contains(cookie.Value, "geektutu") {
+		t.Fatal("cookie should be encrypted")
+	}
+	tampered := *cookie
+	tampered.Value = "1" + cookie.Value
+	if w, _ := do(e, "/me", &tampered); w.Body.String() != " []" {
+		t.Fatalf("tampered cookie should be ignored, got %q", w.Body.String())
+	}
+
+	// 轮换密钥之后旧的 cookie 依然可以读取
+	rotated, _ := NewCookieStore([]byte("new-hash-key"), []byte("fedcba9876543210"), []byte("hash-key"), []byte("0123456789abcdef"))
+	if w, _ := do(newEngine(rotated), "/me", cookie); w.Body.String() != "geektutu [welcome]" {
+		t.Fatalf("old cookie should be accepted after rotation, got %q", w.Body.String())
+	}
+	// 过期的 cookie 被拒绝
+	rotated.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if w, _ := do(newEngine(rotated), "/me", cookie); w.Body.String() != " []" {
+		t.Fatalf("expired cookie should be ignored, got %q", w.Body.String())
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	backend := NewMemoryBackend()
+	store := NewServerStore(backend)
+	testStore(t, store)
+
+	e := newEngine(store)
+	_, cookie := do(e, "/login", nil)
+	if w, _ := do(e, "/me", cookie); w.Body.String() != "geektutu [welcome]" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	backend.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if w, _ := do(e, "/me", cookie); w.Body.String() != " []" {
+		t.Fatalf("expired session should be ignored, got %q", w.Body.String())
+	}
+	if _, err := backend.Get(cookie.Value); err != ErrNotFound {
+		t.Fatalf("expired session should be deleted, got %v", err)
+	}
+}
+
+func TestSessionsWithTimeout(t *testing.T) {
+	store, _ := NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef"))
+	saved := make(chan error, 1)
+	e := geeweb.New()
+	e.Use(Sessions("geeweb_session", store, Options{}))
+	e.Use(geeweb.Timeout(50 * time.Millisecond))
+	e.GET("/login", func(ctx *geeweb.Context) {
+		s := Default(ctx)
+		s.Set("user", "geektutu")
+		_ = s.Save()
+	})
+	e.GET("/me", func(ctx *geeweb.Context) {
+		user, _ := Default(ctx).Get("user").(string)
+		ctx.String(http.StatusOK, "%s", user)
+	})
+	e.GET("/slow", func(ctx *geeweb.Context) {
+		s := Default(ctx)
+		<-ctx.Done()
+		s.Set("user", "late")
+		saved <- s.Save()
+	})
+
+	w, cookie := do(e, "/login", nil)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatalf("session saved behind Timeout should set the cookie, got %v", w.Header())
+	}
+	if w, _ = do(e, "/me", cookie); w.Body.String() != "geektutu" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	w, _ = do(e, "/slow", nil)
+	<-saved
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("save after timeout should be discarded, got %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,129 @@
+package geeweb
+
+import (
+	"encoding/json"
+	"gee/render"
+	"io"
+	"time"
+)
+
+const MIMENDJSON = "application/x-ndjson"
+
+// Flush 把缓冲的数据立即发送给客户端
+func (c *Context) Flush() {
+	c.Response.Flush()
+}
+
+// prepareStream 在第一次写出之前设置流式响应需要的头部
+func (c *Context) prepareStream(contentType string) {
+	header := c.Response.Header()
+	if header.Get("Content-Type") != "" {
+		return
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // 关闭 nginx 的缓冲
+}
+
+// SSEvent 发送一个事件并立即 Flush
+func (c *Context) SSEvent(name string, data interface{}) error {
+	return c.SSE(render.SSEvent{Event: name, Data: data})
+}
+
+func (c *Context) SSE(event render.SSEvent) error {
+	c.prepareStream("text/event-stream")
+	if err := event.Encode(c.Response); err != nil {
+		return err
+	}
+	c.Flush()
+	return nil
+}
+
+// LastEventID 返回客户端断线重连时带上的最后一个事件 ID
+func (c *Context) LastEventID() string {
+	if id := c.Request.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("lastEventId")
+}
+
+// SSEStream 持续发送 events 中的事件，每隔 keepAlive 没有事件时发送一行注释防止连接被代理断开
+// events 被关闭时返回 false，客户端断开时返回 true
+func (c *Context) SSEStream(keepAlive time.Duration, events <-chan render.SSEvent) bool {
+	c.prepareStream("text/event-stream")
+	c.Flush()
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.SSE(event); err != nil {
+				return true
+			}
+		case <-tick:
+			if err := render.SSEComment(c.Response, "keep-alive"); err != nil {
+				return true
+			}
+			c.Flush()
+		}
+	}
+}
+
+// Stream 反复调用 step 直到它返回 false，每次调用之后自动 Flush
+// 客户端断开时停止并返回 true
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.Response)
+			c.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// NDJSON 写出一行 JSON，用于 application/x-ndjson 流式响应
+func (c *Context) NDJSON(v interface{}) error {
+	c.prepareStream(MIMENDJSON)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err = c.Response.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	c.Flush()
+	return nil
+}
+
+// StreamNDJSON 把 items 中的每个值写成一行 JSON，直到 items 被关闭或者客户端断开
+func (c *Context) StreamNDJSON(items <-chan interface{}) bool {
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case item, ok := <-items:
+			if !ok {
+				return false
+			}
+			if err := c.NDJSON(item); err != nil {
+				return true
+			}
+		}
+	}
+}
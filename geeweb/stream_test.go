@@ -0,0 +1,93 @@
+package geeweb
+
+import (
+	"context"
+	"gee/render"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEvent(t *testing.T) {
+	e := New()
+	e.GET("/events", func(ctx *Context) {
+		_ = ctx.SSEvent("message", "line1\nline2")
+		_ = ctx.SSE(render.SSEvent{Event: "progress", ID: ctx.LastEventID() + "1", Data: H{"done": 1}})
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "4")
+	e.ServeHTTP(w, req)
+	expect := "event: message\ndata: line1\ndata: line2\n\nid: 41\nevent: progress\ndata: {\"done\":1}\n\n"
+	if w.Body.String() != expect || !w.Flushed {
+		t.Fatalf("unexpected sse body %q", w.Body.String())
+	}
+	if w.Header().Get("Connection") != "" {
+		t.Fatal("hop-by-hop Connection header is not allowed in HTTP/2 responses")
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestStreamStopsOnClientGone(t *testing.T) {
+	e := New()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	steps := 0
+	var gone bool
+	e.GET("/stream", func(ctx *Context) {
+		gone = ctx.Stream(func(w io.Writer) bool {
+			steps++
+			if steps == 3 {
+				cancel()
+			}
+			_ = ctx.NDJSON(H{"step": steps})
+			return true
+		})
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(reqCtx))
+	if !gone || steps != 3 {
+		t.Fatalf("stream should stop after the client is gone, gone=%v steps=%d", gone, steps)
+	}
+	if w.Body.String() != "{\"step\":1}\n{\"step\":2}\n{\"step\":3}\n" {
+		t.Fatalf("unexpected ndjson body %q", w.Body.String())
+	}
+}
+
+func TestSSEStreamKeepAlive(t *testing.T) {
+	e := New()
+	events := make(chan render.SSEvent)
+	e.GET("/events", func(ctx *Context) {
+		if ctx.SSEStream(10*time.Millisecond, events) {
+			t.Error("stream should end because events is closed")
+		}
+	})
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		events <- render.SSEvent{Data: "hi"}
+		close(events)
+	}()
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	body := w.Body.String()
+	if len(body) < len(": keep-alive\n\ndata: hi\n\n") || body[:len(": keep-alive\n\n")] != ": keep-alive\n\n" {
+		t.Fatalf("expect keep-alive comments before the event, got %q", body)
+	}
+}
+
+func TestSSEventLineEndings(t *testing.T) {
+	for data, expect := range map[string]string{
+		"a\r\nb":   "data: a\ndata: b\n\n",
+		"a\rb":     "data: a\ndata: b\n\n",
+		"a\r\rb\n": "data: a\ndata: \ndata: b\ndata: \n\n",
+	} {
+		var b strings.Builder
+		if err := (render.SSEvent{Data: data}).Encode(&b); err != nil || b.String() != expect {
+			t.Fatalf("%q: expect %q, got %q %v", data, expect, b.String(), err)
+		}
+	}
+}
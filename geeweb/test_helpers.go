@@ -0,0 +1,12 @@
+package geeweb
+
+import "net/http"
+
+// CreateTestContext 返回写入 w 的 Context，用于不经过路由单独测试一个 handler。
+// 状态码在第一次写入时才写出，handler 只设置状态码时需要调用 Response.WriteHeaderNow
+func CreateTestContext(w http.ResponseWriter, req *http.Request) (*Context, *Engine) {
+	e := New()
+	c := &Context{engine: e}
+	c.reset(w, req)
+	return c, e
+}
@@ -0,0 +1,276 @@
+package geeweb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrHandlerTimeout 是超时之后 handler 继续写入时返回的错误，也是超时响应中记录的错误
+var ErrHandlerTimeout = errors.New("geeweb: handler timeout")
+
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// StatusCode 是超时的状态码，默认为 503，网关类的服务可以使用 504
+	StatusCode int
+	// Handler 不为空时由它写出超时响应，否则交给 ErrorHandler
+	Handler HandlerFunc
+}
+
+// Timeout 为之后的 handler 设置超时，Request.Context() 和 Context 本身都会在超时后被取消
+func Timeout(timeout time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig 在新的 goroutine 中执行剩余的 handler，响应先写入缓冲区，
+// 按时完成时再写出；超时之后立即返回超时响应，handler 之后的写入都会被丢弃。
+// 流式响应和 WebSocket 需要直接访问连接，不能放在 Timeout 之后。
+//
+// 之后的 handler 拿到的是 Context 的副本。Keys 中持有原来 *Context 的值（闭包、会话等）
+// 需要实现 ContextBinder，否则它们在新的 goroutine 中写到原来的 Context 上，
+// 超时之后这些写入会和超时响应并发。原来的 Context 要等 handler 返回之后才会被复用
+func TimeoutWithConfig(config TimeoutConfig) HandlerFunc {
+	if config.Timeout <= 0 {
+		panic("geeweb: timeout must be positive")
+	}
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	return func(c *Context) {
+		ctx := newTimeoutContext(c.Request.Context(), config.Timeout)
+		defer ctx.cancel()
+		timer := time.NewTimer(config.Timeout)
+		defer timer.Stop()
+
+		tw := &timeoutWriter{header: make(http.Header), status: http.StatusOK}
+		for k, v := range c.Response.Header() {
+			tw.header[k] = append([]string(nil), v...)
+		}
+		detached := c.detach(tw, c.Request.WithContext(ctx))
+		done := make(chan struct{})
+		finished := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer close(finished)
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+					return
+				}
+				close(done)
+			}()
+			detached.Next()
+		}()
+
+		select {
+		case p := <-panicChan:
+			// 交给当前 goroutine 中的 Recovery 处理
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			// 合并而不是替换，保留其他 goroutine 直接写到 c.Response 上的头部
+			header := c.Response.Header()
+			for k, v := range tw.header {
+				header[k] = v
+			}
+			c.Response.WriteHeader(tw.status)
+			if tw.wroteHeader {
+				_, _ = c.Response.Write(tw.buf.Bytes())
+			}
+			c.Keys = bindKeys(detached.Keys, c)
+			c.Errors = append(c.Errors, detached.Errors...)
+			c.index = detached.index
+		case <-c.Request.Context().Done():
+			// 客户端已经断开，不需要响应
+			c.busy = finished
+			tw.discard()
+			ctx.cancel()
+			c.Abort()
+		case <-timer.C:
+			// 先丢弃之后的写入再取消，handler 观察到取消时一定已经不能再写入
+			c.busy = finished
+			tw.discard()
+			atomic.StoreInt32(&ctx.expired, 1)
+			ctx.cancel()
+			c.Abort()
+			if config.Handler != nil {
+				c.Response.WriteHeader(config.StatusCode)
+				config.Handler(c)
+				return
+			}
+			_ = c.AbortWithError(config.StatusCode, ErrHandlerTimeout)
+		}
+	}
+}
+
+// timeoutContext 的 Deadline 返回超时时间，但只由 Timeout 取消，超时之后 Err 返回 context.DeadlineExceeded
+type timeoutContext struct {
+	context.Context
+	cancel   context.CancelFunc
+	deadline time.Time
+	expired  int32
+}
+
+func newTimeoutContext(parent context.Context, timeout time.Duration) *timeoutContext {
+	ctx, cancel := context.WithCancel(parent)
+	deadline := time.Now().Add(timeout)
+	if d, ok := parent.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return &timeoutContext{Context: ctx, cancel: cancel, deadline: deadline}
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && atomic.LoadInt32(&c.expired) == 1 {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// detach 返回在其他 goroutine 中继续执行处理链的副本，副本不会被放回 Engine 的 pool
+func (c *Context) detach(w ResponseWriter, req *http.Request) *Context {
+	cp := &Context{
+		Response:   w,
+		Request:    req,
+		Method:     c.Method,
+		Path:       c.Path,
+		Params:     c.Params,
+		StatusCode: c.StatusCode,
+		fullPath:   c.fullPath,
+		handlers:   c.handlers,
+		index:      c.index,
+		engine:     c.engine,
+		sameSite:   c.sameSite,
+	}
+	c.mu.RLock()
+	cp.Keys = bindKeys(c.Keys, cp)
+	c.mu.RUnlock()
+	for name, fn := range c.funcMap {
+		cp.SetTemplateFunc(name, fn)
+	}
+	return cp
+}
+
+// ContextBinder 由保存在 Keys 中并持有 *Context 的值实现，比如会话。
+// Timeout 复制 Context 时用 BindContext 返回的值替换它，这样在新的 goroutine 中写出的内容不会落到原来的 Context 上
+type ContextBinder interface {
+	BindContext(c *Context) interface{}
+}
+
+// bindKeys 复制 keys，并把其中的 ContextBinder 绑定到 c
+func bindKeys(keys map[string]interface{}, c *Context) map[string]interface{} {
+	if keys == nil {
+		return nil
+	}
+	cp := make(map[string]interface{}, len(keys))
+	for k, v := range keys {
+		if binder, ok := v.(ContextBinder); ok {
+			v = binder.BindContext(c)
+		}
+		cp[k] = v
+	}
+	return cp
+}
+
+// timeoutWriter 缓冲响应，超时之后的写入返回 ErrHandlerTimeout
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+var _ ResponseWriter = (*timeoutWriter)(nil)
+
+func (w *timeoutWriter) discard() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader || code <= 0 {
+		return
+	}
+	w.status = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.buf.Write(data)
+}
+
+func (w *timeoutWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		return noWritten
+	}
+	return w.buf.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// Flush 不能真正发送，响应在 handler 完成之后一次写出
+func (w *timeoutWriter) Flush() {
+	w.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("geeweb: Hijack is not supported after Timeout")
+}
+
+func (w *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	return http.ErrNotSupported
+}
+
+// Unwrap 返回 nil，超时之前写出的内容只存在于缓冲区中
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return nil
+}
@@ -0,0 +1,124 @@
+package geeweb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	lateWrite := make(chan error, 1)
+	e := New()
+	e.Use(func(ctx *Context) {
+		ctx.Next()
+		ctx.SetHeader("X-User", ctx.GetString("user"))
+	})
+	e.Use(Timeout(50 * time.Millisecond))
+	e.GET("/fast", func(ctx *Context) {
+		ctx.Set("user", "geektutu")
+		ctx.SetHeader("X-Fast", "1")
+		ctx.String(http.StatusCreated, "fast")
+	})
+	e.GET("/slow", func(ctx *Context) {
+		<-ctx.Done()
+		_, err := ctx.Response.Write([]byte("late"))
+		lateWrite <- err
+	})
+	e.GET("/panic", func(ctx *Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Fatalf("fast handler got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	if w.Header().Get("X-User") != "geektutu" {
+		t.Fatal("keys set after Timeout should be visible to earlier middleware")
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "Service Unavailable\n" {
+		t.Fatalf("slow handler should time out, got %d %q", w.Code, w.Body.String())
+	}
+	if err := <-lateWrite; err != ErrHandlerTimeout {
+		t.Fatalf("late write should be discarded, got %v", err)
+	}
+}
+
+func TestTimeoutCustomResponse(t *testing.T) {
+	e := New()
+	e.Use(Recovery())
+	e.Use(TimeoutWithConfig(TimeoutConfig{
+		Timeout:    20 * time.Millisecond,
+		StatusCode: http.StatusGatewayTimeout,
+		Handler:    func(ctx *Context) { ctx.JSON(http.StatusGatewayTimeout, H{"error": "timeout"}) },
+	}))
+	e.GET("/slow", func(ctx *Context) { <-ctx.Done() })
+	e.GET("/panic", func(ctx *Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout || w.Body.String() != "{\"error\":\"timeout\"}\n" {
+		t.Fatalf("expect custom 504, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("panic should be recovered, got %d", w.Code)
+	}
+}
+
+func TestClientGone(t *testing.T) {
+	gone := make(chan bool, 1)
+	e := New()
+	e.GET("/", func(ctx *Context) {
+		<-ctx.Done()
+		gone <- ctx.ClientGone()
+	})
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if !<-gone {
+		t.Fatal("ClientGone should be true after the request is canceled")
+	}
+}
+
+func TestTimeoutContextNotReused(t *testing.T) {
+	proceed, written := make(chan struct{}), make(chan struct{})
+	e := New()
+	e.Use(func(ctx *Context) {
+		// 闭包持有原来的 Context，Timeout 不能替换它
+		ctx.Set("set", func(key string, value interface{}) { ctx.Set(key, value) })
+		ctx.Next()
+	})
+	e.GET("/slow", Timeout(20*time.Millisecond), func(ctx *Context) {
+		<-ctx.Done()
+		<-proceed
+		ctx.MustGet("set").(func(string, interface{}))("user", "late")
+		close(written)
+	})
+	e.GET("/next", func(ctx *Context) {
+		// 超时的 handler 在下一个请求执行时才写入
+		close(proceed)
+		<-written
+		ctx.String(http.StatusOK, "%s", ctx.GetString("user"))
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expect 503, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/next", nil))
+	if w.Body.String() != "" {
+		t.Fatalf("timed out handler wrote into the next request, got %q", w.Body.String())
+	}
+}
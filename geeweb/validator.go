@@ -0,0 +1,161 @@
+package geeweb
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FieldError 描述一个字段没有通过 binding 标签中的某条规则
+type FieldError struct {
+	Field string // 带结构体路径的字段名，如 User.Address.City
+	Tag   string // 规则名，如 required、min
+	Param string // 规则参数，如 min=1 中的 1
+	Value interface{}
+}
+
+func (e FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field '%s' failed on the '%s' rule", e.Field, e.Tag)
+	}
+	return fmt.Sprintf("field '%s' failed on the '%s=%s' rule", e.Field, e.Tag, e.Param)
+}
+
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
+// validate 根据 binding 标签校验结构体，支持 required,omitempty,min,max,len,email,oneof
+// 规则对零值同样生效，可选的字段需要加上 omitempty，此时零值跳过之后的所有规则
+func validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	validateStruct(v, v.Type().Name(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, namespace string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		name := namespace + "." + field.Name
+		if field.Anonymous {
+			name = namespace
+		}
+		if rules := field.Tag.Get("binding"); rules != "" && rules != "-" {
+			validateField(fv, name, rules, errs)
+		}
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			validateStruct(fv, name, errs)
+		}
+	}
+}
+
+func validateField(v reflect.Value, name string, rules string, errs *ValidationErrors) {
+	isZero := v.IsZero()
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			tag, param = rule[:i], rule[i+1:]
+		}
+		if tag == "omitempty" {
+			if isZero {
+				return
+			}
+			continue
+		}
+		ok, err := checkRule(v, tag, param)
+		if err != nil {
+			panic(fmt.Sprintf("geeweb: bad binding rule %q on %s: %v", rule, name, err))
+		}
+		if !ok {
+			*errs = append(*errs, FieldError{Field: name, Tag: tag, Param: param, Value: v.Interface()})
+		}
+	}
+}
+
+func checkRule(v reflect.Value, tag, param string) (bool, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return tag != "required", nil
+		}
+		v = v.Elem()
+	}
+	switch tag {
+	case "required":
+		return !v.IsZero(), nil
+	case "min", "max", "len":
+		return compare(v, tag, param)
+	case "email":
+		return v.Kind() == reflect.String && emailRegexp.MatchString(v.String()), nil
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown rule %q", tag)
+}
+
+// compare 对字符串、切片和 map 比较长度，对数字比较大小
+func compare(v reflect.Value, tag, param string) (bool, error) {
+	var actual, limit float64
+	switch v.Kind() {
+	case reflect.String:
+		actual = float64(len([]rune(v.String())))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		actual = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = v.Float()
+	default:
+		return false, fmt.Errorf("%s is not comparable", v.Type())
+	}
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false, err
+	}
+	switch tag {
+	case "min":
+		return actual >= limit, nil
+	case "max":
+		return actual <= limit, nil
+	default:
+		return actual == limit, nil
+	}
+}
@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Dialer 用于建立客户端连接，主要用于测试和服务之间的调用
+type Dialer struct {
+	HandshakeTimeout time.Duration
+	TLSConfig        *tls.Config
+	Subprotocols     []string
+	ReadLimit        int64
+}
+
+var DefaultDialer = &Dialer{HandshakeTimeout: 10 * time.Second}
+
+// Dial 使用 DefaultDialer 连接 ws:// 或 wss:// 地址
+func Dial(urlStr string, requestHeader http.Header) (*Conn, *http.Response, error) {
+	return DefaultDialer.Dial(urlStr, requestHeader)
+}
+
+func (d *Dialer) Dial(urlStr string, requestHeader http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	secure := false
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "http"
+	case "wss", "https":
+		u.Scheme, secure = "https", true
+	default:
+		return nil, nil, errors.New("websocket: bad scheme " + u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		if secure {
+			host += ":443"
+		} else {
+			host += ":80"
+		}
+	}
+
+	netDialer := &net.Dialer{Timeout: d.HandshakeTimeout}
+	var netConn net.Conn
+	if secure {
+		cfg := d.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: u.Hostname()}
+		}
+		netConn, err = tls.DialWithDialer(netDialer, "tcp", host, cfg)
+	} else {
+		netConn, err = netDialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.HandshakeTimeout > 0 {
+		_ = netConn.SetDeadline(time.Now().Add(d.HandshakeTimeout))
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		_ = netConn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, v := range requestHeader {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	if err := req.Write(netConn); err != nil {
+		_ = netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+		!headerContainsToken(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-Websocket-Accept") != computeAcceptKey(key) {
+		_ = netConn.Close()
+		return nil, resp, ErrBadHandshake
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	c := newConn(netConn, br, false, d.ReadLimit)
+	c.subprotocol = resp.Header.Get("Sec-Websocket-Protocol")
+	return c, resp, nil
+}
@@ -0,0 +1,449 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 消息类型，与 RFC 6455 中的 opcode 一致
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// 关闭码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finalBit = 0x80
+	rsvBits  = 0x70
+	maskBit  = 0x80
+
+	maxControlPayload = 125
+	defaultReadLimit  = 32 << 20
+)
+
+var (
+	ErrCloseSent = errors.New("websocket: close sent")
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+)
+
+// CloseError 表示收到了对方的关闭帧
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.msg
+}
+
+// Conn 是一个 WebSocket 连接，读和写可以分别在两个 goroutine 中并发进行
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+
+	readLimit   int64
+	readErr     error
+	pingHandler func(appData string) error
+	pongHandler func(appData string) error
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool, readLimit int64) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	if readLimit <= 0 {
+		readLimit = defaultReadLimit
+	}
+	c := &Conn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: readLimit,
+	}
+	c.pingHandler = func(appData string) error {
+		err := c.writeFrame(PongMessage, []byte(appData))
+		if err == ErrCloseSent {
+			return nil
+		}
+		return err
+	}
+	return c
+}
+
+func (c *Conn) Subprotocol() string { return c.subprotocol }
+
+func (c *Conn) LocalAddr() net.Addr { return c.conn.LocalAddr() }
+
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// SetReadLimit 设置单条消息（所有分片之和）的最大字节数，超过时以 1009 关闭连接
+func (c *Conn) SetReadLimit(limit int64) { c.readLimit = limit }
+
+// SetPingHandler 默认的 handler 会回复 pong
+func (c *Conn) SetPingHandler(h func(appData string) error) { c.pingHandler = h }
+
+func (c *Conn) SetPongHandler(h func(appData string) error) { c.pongHandler = h }
+
+// Close 直接关闭底层连接，不发送关闭帧，正常关闭应先调用 WriteClose
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+func isControl(opcode int) bool {
+	return opcode >= CloseMessage
+}
+
+func (c *Conn) readFrame(remaining int64) (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return nil, err
+	}
+	f := &frame{fin: head[0]&finalBit != 0, opcode: int(head[0] & 0x0f)}
+	if head[0]&rsvBits != 0 {
+		return nil, &protocolError{CloseProtocolError, "unexpected reserved bits"}
+	}
+	masked := head[1]&maskBit != 0
+	if masked != c.isServer {
+		return nil, &protocolError{CloseProtocolError, "bad frame masking"}
+	}
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return nil, &protocolError{CloseProtocolError, "invalid payload length"}
+		}
+	}
+	if isControl(f.opcode) {
+		if !f.fin {
+			return nil, &protocolError{CloseProtocolError, "fragmented control frame"}
+		}
+		if length > maxControlPayload {
+			return nil, &protocolError{CloseProtocolError, "control frame too long"}
+		}
+	} else if length > remaining {
+		// 在分配内存之前就检查长度，避免恶意的超大帧
+		return nil, ErrReadLimit
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return nil, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		maskBytes(mask, f.payload)
+	}
+	return f, nil
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// ReadMessage 读取一条完整的消息，期间收到的 ping、pong 和关闭帧会被自动处理
+// 收到关闭帧时会回复关闭帧并返回 *CloseError
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	for {
+		f, err := c.readFrame(c.readLimit - int64(len(p)))
+		if err != nil {
+			return 0, nil, c.failRead(err)
+		}
+		switch f.opcode {
+		case PingMessage:
+			if c.pingHandler != nil {
+				if err := c.pingHandler(string(f.payload)); err != nil {
+					return 0, nil, c.failRead(err)
+				}
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				if err := c.pongHandler(string(f.payload)); err != nil {
+					return 0, nil, c.failRead(err)
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.failRead(c.handleClose(f.payload))
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.failRead(&protocolError{CloseProtocolError, "expected continuation frame"})
+			}
+			messageType = f.opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.failRead(&protocolError{CloseProtocolError, "unexpected continuation frame"})
+			}
+		default:
+			return 0, nil, c.failRead(&protocolError{CloseProtocolError, fmt.Sprintf("unknown opcode %d", f.opcode)})
+		}
+		p = append(p, f.payload...)
+		if f.fin {
+			if messageType == TextMessage && !utf8.Valid(p) {
+				return 0, nil, c.failRead(&protocolError{CloseInvalidFramePayloadData, "invalid utf8 payload"})
+			}
+			return messageType, p, nil
+		}
+	}
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// handleClose 回复关闭帧，返回的错误会作为之后所有读操作的结果
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	var reply []byte
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return &protocolError{CloseProtocolError, "invalid close code"}
+		}
+		if !utf8.Valid(payload[2:]) {
+			return &protocolError{CloseInvalidFramePayloadData, "invalid utf8 close reason"}
+		}
+		reply = FormatCloseMessage(closeErr.Code, "")
+	} else if len(payload) == 1 {
+		return &protocolError{CloseProtocolError, "invalid close payload"}
+	}
+	if err := c.writeFrame(CloseMessage, reply); err != nil && err != ErrCloseSent {
+		return err
+	}
+	_ = c.conn.Close()
+	return closeErr
+}
+
+// failRead 在协议错误时发送对应的关闭帧并关闭连接
+func (c *Conn) failRead(err error) error {
+	switch e := err.(type) {
+	case *protocolError:
+		_ = c.WriteClose(e.code, e.msg)
+		_ = c.conn.Close()
+	case *CloseError:
+	default:
+		if err == ErrReadLimit {
+			_ = c.WriteClose(CloseMessageTooBig, "message too big")
+		} else if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+		}
+		_ = c.conn.Close()
+	}
+	c.readErr = err
+	return err
+}
+
+// FormatCloseMessage 生成关闭帧的负载
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	return c.writeFragment(opcode, payload, true)
+}
+
+func (c *Conn) writeFragment(opcode int, payload []byte, fin bool) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	header := make([]byte, 0, 14)
+	b0 := byte(opcode)
+	if fin {
+		b0 |= finalBit
+	}
+	header = append(header, b0)
+	var b1 byte
+	if !c.isServer {
+		b1 = maskBit
+	}
+	switch n := len(payload); {
+	case n <= maxControlPayload:
+		header = append(header, b1|byte(n))
+	case n <= 0xffff:
+		header = append(header, b1|126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, b1|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	// 客户端发送的帧必须掩码，且不能修改调用者的数据
+	if !c.isServer {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		header = append(header, mask[:]...)
+		masked := make([]byte, len(payload))
+		copy(masked, payload)
+		maskBytes(mask, masked)
+		payload = masked
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteMessage 以单个帧发送一条消息
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+		return c.writeFrame(messageType, data)
+	case PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return errors.New("websocket: control frame too long")
+		}
+		return c.writeFrame(messageType, data)
+	case CloseMessage:
+		return c.writeFrame(messageType, data)
+	}
+	return fmt.Errorf("websocket: unknown message type %d", messageType)
+}
+
+// WriteFragmented 把一条消息拆成最多 size 字节的若干个分片发送
+func (c *Conn) WriteFragmented(messageType int, data []byte, size int) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: cannot fragment message type %d", messageType)
+	}
+	if size <= 0 || len(data) <= size {
+		return c.writeFrame(messageType, data)
+	}
+	opcode := messageType
+	for len(data) > size {
+		if err := c.writeFragment(opcode, data[:size], false); err != nil {
+			return err
+		}
+		opcode, data = continuationFrame, data[size:]
+	}
+	return c.writeFragment(opcode, data, true)
+}
+
+func (c *Conn) WritePing(data []byte) error {
+	return c.WriteMessage(PingMessage, data)
+}
+
+// WriteClose 发送关闭帧，对方回复的关闭帧会使 ReadMessage 返回 *CloseError
+func (c *Conn) WriteClose(code int, text string) error {
+	if len(text) > maxControlPayload-2 {
+		text = text[:maxControlPayload-2]
+	}
+	return c.writeFrame(CloseMessage, FormatCloseMessage(code, text))
+}
+
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// IsCloseError 判断 err 是否是带有 codes 中某个关闭码的 *CloseError
+func IsCloseError(err error, codes ...int) bool {
+	if e, ok := err.(*CloseError); ok {
+		for _, code := range codes {
+			if e.Code == code {
+				return true
+			}
+		}
+	}
+	return false
+}
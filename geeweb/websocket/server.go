@@ -0,0 +1,150 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError 表示升级请求不合法，Upgrade 已经向客户端返回了错误响应
+type HandshakeError struct {
+	message string
+}
+
+func (e HandshakeError) Error() string { return e.message }
+
+// Upgrader 把 HTTP 请求升级为 WebSocket 连接，零值可以直接使用
+type Upgrader struct {
+	// ReadLimit 是单条消息的最大字节数，0 表示默认的 32MB
+	ReadLimit int64
+	// Subprotocols 按优先级排列服务端支持的子协议
+	Subprotocols []string
+	// CheckOrigin 为空时只允许同源请求或者没有 Origin 头的请求
+	CheckOrigin func(r *http.Request) bool
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContainsToken 判断逗号分隔的头部中是否包含 token，不区分大小写
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header[http.CanonicalHeaderKey(name)] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	requested := make(map[string]bool)
+	for _, value := range r.Header["Sec-Websocket-Protocol"] {
+		for _, v := range strings.Split(value, ",") {
+			requested[strings.TrimSpace(v)] = true
+		}
+	}
+	for _, p := range u.Subprotocols {
+		if requested[p] {
+			return p
+		}
+	}
+	return ""
+}
+
+func (u *Upgrader) fail(w http.ResponseWriter, status int, reason string) (*Conn, error) {
+	w.Header().Set("Sec-WebSocket-Version", "13")
+	http.Error(w, http.StatusText(status), status)
+	return nil, HandshakeError{"websocket: " + reason}
+}
+
+// Upgrade 完成握手并接管底层连接，responseHeader 会附加在 101 响应中
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return u.fail(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return u.fail(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return u.fail(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		return u.fail(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return u.fail(w, http.StatusForbidden, "request origin not allowed")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return u.fail(w, http.StatusBadRequest, "invalid 'Sec-WebSocket-Key' header")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return u.fail(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	if brw.Reader.Buffered() > 0 {
+		_ = netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+
+	c := newConn(netConn, brw.Reader, true, u.ReadLimit)
+	c.subprotocol = u.selectSubprotocol(r)
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n")
+	if c.subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + c.subprotocol + "\r\n")
+	}
+	for k, values := range responseHeader {
+		if k == "Sec-Websocket-Protocol" {
+			continue
+		}
+		for _, v := range values {
+			b.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// IsWebSocketUpgrade 判断请求是否是 WebSocket 升级请求
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
@@ -0,0 +1,177 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEchoServer(t *testing.T, u *Upgrader) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := u.Upgrade(w, r, http.Header{"X-Test": {"1"}})
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			typ, p, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(typ, p); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func wsURL(s *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func TestEcho(t *testing.T) {
+	s := newEchoServer(t, &Upgrader{Subprotocols: []string{"chat"}})
+	defer s.Close()
+
+	d := &Dialer{Subprotocols: []string{"superchat", "chat"}}
+	c, resp, err := d.Dial(wsURL(s), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Subprotocol() != "chat" || resp.Header.Get("X-Test") != "1" {
+		t.Fatalf("unexpected handshake response, subprotocol %q", c.Subprotocol())
+	}
+
+	if err := c.WriteJSON(map[string]string{"hello": "world"}); err != nil {
+		t.Fatal(err)
+	}
+	var reply map[string]string
+	if err := c.ReadJSON(&reply); err != nil || reply["hello"] != "world" {
+		t.Fatalf("unexpected echo %v %v", reply, err)
+	}
+
+	big := strings.Repeat("geektutu", 10000)
+	if err := c.WriteFragmented(TextMessage, []byte(big), 1000); err != nil {
+		t.Fatal(err)
+	}
+	typ, p, err := c.ReadMessage()
+	if err != nil || typ != TextMessage || string(p) != big {
+		t.Fatalf("fragmented message should be reassembled, got type %d len %d err %v", typ, len(p), err)
+	}
+}
+
+func TestPingPongAndClose(t *testing.T) {
+	s := newEchoServer(t, &Upgrader{})
+	defer s.Close()
+	c, _, err := Dial(wsURL(s), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pong := make(chan string, 1)
+	c.SetPongHandler(func(data string) error {
+		pong <- data
+		return nil
+	})
+	if err := c.WritePing([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteClose(CloseGoingAway, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = c.ReadMessage()
+	if !IsCloseError(err, CloseGoingAway) {
+		t.Fatalf("server should echo close code, got %v", err)
+	}
+	select {
+	case data := <-pong:
+		if data != "ping" {
+			t.Fatalf("unexpected pong %q", data)
+		}
+	default:
+		t.Fatal("pong should arrive before close")
+	}
+	if err := c.WriteMessage(TextMessage, []byte("late")); err != ErrCloseSent {
+		t.Fatalf("writing after close should fail, got %v", err)
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	s := newEchoServer(t, &Upgrader{ReadLimit: 16})
+	defer s.Close()
+	c, _, err := Dial(wsURL(s), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.WriteMessage(BinaryMessage, make([]byte, 17)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadMessage(); !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("expect close 1009, got %v", err)
+	}
+}
+
+func TestUnmaskedClientFrame(t *testing.T) {
+	s := newEchoServer(t, &Upgrader{})
+	defer s.Close()
+	c, _, err := Dial(wsURL(s), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// 伪装成服务端发送未掩码的帧
+	c.isServer = true
+	if err := c.WriteMessage(TextMessage, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	c.isServer = false
+	if _, _, err := c.ReadMessage(); !IsCloseError(err, CloseProtocolError) {
+		t.Fatalf("expect close 1002, got %v", err)
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	s := newEchoServer(t, &Upgrader{})
+	defer s.Close()
+
+	resp, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET should be rejected, got %d", resp.StatusCode)
+	}
+
+	_, resp, err = Dial(wsURL(s), http.Header{"Origin": {"http://evil.example.com"}})
+	if err != ErrBadHandshake || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin request should be rejected, got %v", err)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	s := newEchoServer(t, &Upgrader{})
+	defer s.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Sec-Websocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", resp.Header.Get("Sec-Websocket-Accept"))
+	}
+}
@@ -0,0 +1,29 @@
+package geeweb
+
+import (
+	"gee/websocket"
+	"net/http"
+)
+
+type WSHandlerFunc func(conn *websocket.Conn, ctx *Context)
+
+// WS 注册一个 WebSocket 路由，分组中间件在升级之前执行，中间件 Abort 时不会升级
+func (g *RouterGroup) WS(pattern string, handler WSHandlerFunc) {
+	g.GET(pattern, func(ctx *Context) {
+		// 升级成功后连接被 Hijack，这里先记录 101，握手失败时会被错误状态码覆盖
+		ctx.Status(http.StatusSwitchingProtocols)
+		conn, err := ctx.engine.upgrader.Upgrade(ctx.Response, ctx.Request, nil)
+		if err != nil {
+			// 握手失败时 Upgrade 已经返回了错误响应
+			ctx.Abort()
+			return
+		}
+		defer conn.Close()
+		handler(conn, ctx)
+	})
+}
+
+// SetUpgrader 设置 WS 路由使用的 Upgrader，比如允许跨域或者限制消息大小
+func (e *Engine) SetUpgrader(upgrader *websocket.Upgrader) {
+	e.upgrader = upgrader
+}
@@ -0,0 +1,48 @@
+package geeweb
+
+import (
+	"gee/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWS(t *testing.T) {
+	e := New()
+	api := e.Group("/api")
+	api.Use(func(ctx *Context) {
+		if ctx.Query("token") != "secret" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		ctx.Set("user", "geektutu")
+	})
+	api.WS("/echo/:room", func(conn *websocket.Conn, ctx *Context) {
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(ctx.GetString("user")+"@"+ctx.Param("room")+": "+string(p)))
+	})
+	s := httptest.NewServer(e)
+	defer s.Close()
+	url := "ws" + strings.TrimPrefix(s.URL, "http") + "/api/echo/go"
+
+	_, resp, err := websocket.Dial(url, nil)
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("middleware should reject the upgrade, got %v", err)
+	}
+
+	conn, _, err := websocket.Dial(url+"?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "geektutu@go: hello" {
+		t.Fatalf("unexpected reply %q %v", p, err)
+	}
+}
@@ -2,7 +2,12 @@ module github.com/pandaye/days7go
 
 go 1.17
 
-require geerpc v0.0.0
+require (
+	gee v0.0.0
+	geecache v0.0.0
+	geerpc v0.0.0
+	geetrace v0.0.0
+)
 
 require google.golang.org/protobuf v1.28.0 // indirect
 
@@ -10,4 +15,5 @@ replace (
 	gee => ./geeweb
 	geecache => ./geecache
 	geerpc => ./geerpc
+	geetrace => ./geetrace
 )
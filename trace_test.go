@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	geeweb "gee"
+	"gee/middleware/tracing"
+	"geecache"
+	pb "geecache/geecachepb"
+	"geerpc"
+	"geetrace"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Scores 通过 geecache 的节点读取分数
+type Scores struct {
+	peers *geecache.HttpPool
+}
+
+func (s *Scores) Get(ctx context.Context, name string, reply *string) error {
+	peer, _ := s.peers.PickPeer(name)
+	res := &pb.Response{}
+	if err := peer.(geecache.ContextPeerGetter).GetContext(ctx, &pb.Request{Group: "scores", Key: name}, res); err != nil {
+		return err
+	}
+	*reply = string(res.Value)
+	return nil
+}
+
+// TestTracePropagation 验证 geeweb -> geerpc -> geecache 在同一条链路中
+func TestTracePropagation(t *testing.T) {
+	exporter := geetrace.NewInMemoryExporter()
+	geetrace.SetDefault(geetrace.NewTracer(exporter))
+	defer geetrace.SetDefault(geetrace.NewTracer(nil))
+
+	geecache.NewGroup(geecache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("630"), nil
+	}), "scores", 2<<10)
+	cacheServer := httptest.NewServer(geecache.NewHttpPool(""))
+	defer cacheServer.Close()
+	peers := geecache.NewHttpPool("self")
+	peers.Set(cacheServer.URL)
+
+	rpcServer := geerpc.NewServer()
+	if err := rpcServer.Register(&Scores{peers: peers}); err != nil {
+		t.Fatal(err)
+	}
+	l, _ := net.Listen("tcp", ":0")
+	go rpcServer.Accept(l)
+	client, err := geerpc.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	e := geeweb.New()
+	e.Use(tracing.Default())
+	e.GET("/scores/:name", func(ctx *geeweb.Context) {
+		var score string
+		if err := client.Call(ctx, "Scores.Get", ctx.Param("name"), &score); err != nil {
+			ctx.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		ctx.String(http.StatusOK, "%s", score)
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scores/Tom", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "630" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+
+	// 每一跳的 span 都是上一跳的子 span，geerpc 的 client 和 server span 同名
+	chain := []struct{ name, kind string }{
+		{"GET /scores/:name", "server"},
+		{"Scores.Get", "client"},
+		{"Scores.Get", "server"},
+		{"geecache.Get scores", "client"},
+		{"geecache.Serve scores", "server"},
+	}
+	spans := exporter.Spans()
+	if len(spans) != len(chain) {
+		t.Fatalf("expect %d spans, got %+v", len(chain), spans)
+	}
+	var traceID, parent string
+	for _, hop := range chain {
+		found := false
+		for _, span := range spans {
+			if span.Name == hop.name && span.Kind == hop.kind && span.ParentID == parent {
+				if traceID != "" && span.TraceID != traceID {
+					t.Fatalf("%s should be in trace %s, got %s", hop.name, traceID, span.TraceID)
+				}
+				traceID, parent, found = span.TraceID, span.SpanID, true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("%s %s span should be a child of the previous hop, got %+v", hop.kind, hop.name, spans)
+		}
+	}
+}